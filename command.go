@@ -0,0 +1,160 @@
+package configo
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/han0110/configo/node"
+	"github.com/han0110/configo/util"
+	"github.com/pkg/errors"
+)
+
+var helpCommandsTemplate = template.Must(template.New("commands").Parse(`Commands:
+{{- range . }}
+	{{ .Name }}	{{ .Description }}
+{{- end }}
+`))
+
+var helpCommandTemplate = template.Must(template.New("command").Parse(`Usage:
+	{{ .Name }} [flags]
+	{{- range .Args }} {{ if eq .Arg "rest" }}[{{ .Name }}...]{{ else }}<{{ .Name }}>{{ end }}{{ end }}
+{{- with .Description }}
+
+{{ . }}
+{{- end }}
+{{- with .Args }}
+
+Arguments:
+{{- range . }}
+	{{ .Name }}	{{ .Description }}
+{{- end }}
+{{- end }}
+
+`))
+
+// Command defines a subcommand with its own config, which usually embeds
+// structs shared by other commands.
+type Command struct {
+	Name        string
+	Description string
+	Config      interface{}
+}
+
+// AddCommand adds a subcommand with its own config.
+func (configo *Configo) AddCommand(name, description string, config interface{}) *Configo {
+	configo.Commands = append(configo.Commands, &Command{
+		Name:        name,
+		Description: description,
+		Config:      config,
+	})
+	return configo
+}
+
+// LoadCommand selects command by the first positional argument, and loads
+// configurations into its config with the rest arguments.
+func (configo *Configo) LoadCommand(args []string) (*Command, error) {
+	var parseErr error
+	for _, command := range configo.Commands {
+		n, err := configo.encode(command.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		// Expand response files with command's node to know flags' values
+		args, err := configo.expandArgs(n, args)
+		if err != nil {
+			return nil, err
+		}
+
+		// Parse with command's node to know which arguments are flags' values,
+		// where command could be interspersed with flags
+		f := newFlagSet(args, n)
+		f.interspersed = true
+		if err := f.parse(); err != nil {
+			if parseErr == nil {
+				parseErr = err
+			}
+			continue
+		}
+		if len(f.positionals) == 0 || f.positionals[0] != command.Name {
+			continue
+		}
+
+		index := f.positionalIndexes[0]
+		commandArgs := append(append([]string{}, args[:index]...), args[index+1:]...)
+		return command, configo.load(n, commandArgs)
+	}
+
+	if parseErr != nil {
+		return nil, parseErr
+	}
+	return nil, errors.Errorf("expected command, one of %s", strings.Join(configo.commandNames(), ", "))
+}
+
+// HelpCommands writes commands with their descriptions.
+func (configo *Configo) HelpCommands(w io.Writer) error {
+	return util.TabExecutor(w, helpCommandsTemplate, configo.Commands)
+}
+
+// HelpCommand writes usage of command with its positional arguments, and its
+// flags like Help.
+func (configo *Configo) HelpCommand(w io.Writer, name string) error {
+	for _, command := range configo.Commands {
+		if command.Name != name {
+			continue
+		}
+
+		n, err := configo.encode(command.Config)
+		if err != nil {
+			return err
+		}
+		args, flags := splitPositionals(n.Flat())
+
+		data := struct {
+			*Command
+			Args []*node.Node
+		}{command, args}
+		if err := util.TabExecutor(w, helpCommandTemplate, data); err != nil {
+			return err
+		}
+		return util.TabExecutor(w, helpTemplate, flags)
+	}
+
+	return errors.Errorf("unknown command %s, expected one of %s", name, strings.Join(configo.commandNames(), ", "))
+}
+
+// splitPositionals splits nodes into ones tagged with arg or args ordered by
+// index with the rest last, and the others.
+func splitPositionals(nodes []*node.Node) (args, flags []*node.Node) {
+	for _, n := range nodes {
+		if n.Arg == "" {
+			flags = append(flags, n)
+		} else {
+			args = append(args, n)
+		}
+	}
+	sort.SliceStable(args, func(i, j int) bool {
+		return argIndex(args[i]) < argIndex(args[j])
+	})
+	return args, flags
+}
+
+func argIndex(n *node.Node) int {
+	if n.Arg == node.ArgRest {
+		return math.MaxInt32
+	}
+	index, _ := strconv.Atoi(n.Arg)
+	return index
+}
+
+func (configo *Configo) commandNames() []string {
+	names := make([]string, len(configo.Commands))
+	for i, command := range configo.Commands {
+		names[i] = command.Name
+	}
+	return names
+}
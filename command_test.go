@@ -0,0 +1,158 @@
+package configo
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type CommonConfig struct {
+	Verbose bool   `yaml:"verbose"`
+	Level   string `yaml:"level"`
+}
+
+type serveConfig struct {
+	CommonConfig
+	Port int `yaml:"port"`
+}
+
+type migrateConfig struct {
+	CommonConfig
+	DryRun bool     `yaml:"dryRun"`
+	Files  []string `yaml:"files" args:"rest"`
+}
+
+func TestLoadCommand(t *testing.T) {
+	testcases := []struct {
+		description string
+		args        []string
+		expectedCmd string
+		expected    interface{}
+		err         error
+	}{
+		{
+			description: "global flags before command",
+			args:        []string{"--verbose", "serve", "--port", "80"},
+			expectedCmd: "serve",
+			expected:    &serveConfig{CommonConfig: CommonConfig{Verbose: true}, Port: 80},
+		},
+		{
+			description: "flag value same as command",
+			args:        []string{"--level", "serve", "migrate", "--dry-run", "a", "b"},
+			expectedCmd: "migrate",
+			expected: &migrateConfig{
+				CommonConfig: CommonConfig{Level: "serve"},
+				DryRun:       true,
+				Files:        []string{"a", "b"},
+			},
+		},
+		{
+			description: "unknown command",
+			args:        []string{"--verbose", "worker"},
+			err:         errors.New("expected command, one of serve, migrate"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			configs := map[string]interface{}{
+				"serve":   &serveConfig{},
+				"migrate": &migrateConfig{},
+			}
+			configo := (&Configo{Loader: &FlagLoader{DisallowUnused: true}}).
+				AddCommand("serve", "serve requests", configs["serve"]).
+				AddCommand("migrate", "migrate database", configs["migrate"])
+
+			command, err := configo.LoadCommand(testcase.args)
+			if testcase.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, testcase.expectedCmd, command.Name)
+				assert.Equal(t, testcase.expected, configs[command.Name])
+			} else {
+				require.EqualError(t, err, testcase.err.Error())
+			}
+		})
+	}
+}
+
+func TestHelpCommands(t *testing.T) {
+	configo := (&Configo{}).
+		AddCommand("serve", "serve requests", &serveConfig{}).
+		AddCommand("migrate", "migrate database", &migrateConfig{})
+
+	var buf bytes.Buffer
+	require.NoError(t, configo.HelpCommands(&buf))
+	assert.Equal(t, "Commands:\n    serve      serve requests\n    migrate    migrate database\n", buf.String())
+}
+
+type copyConfig struct {
+	CommonConfig
+	Input  string   `yaml:"input" arg:"0" description:"source file"`
+	Output string   `yaml:"output" arg:"1" description:"destination file"`
+	Extra  []string `yaml:"extra" args:"rest" description:"extra files"`
+}
+
+func TestHelpCommand(t *testing.T) {
+	testcases := []struct {
+		description string
+		name        string
+		expected    string
+		err         error
+	}{
+		{
+			description: "flags only",
+			name:        "serve",
+			expected: "Usage:\n" +
+				"    serve [flags]\n" +
+				"\n" +
+				"serve requests\n" +
+				"\n" +
+				"Flags:\n" +
+				"    --level      \n" +
+				"    --port       \n" +
+				"    --verbose    \n",
+		},
+		{
+			description: "positional arguments",
+			name:        "copy",
+			expected: "Usage:\n" +
+				"    copy [flags] <input> <output> [extra...]\n" +
+				"\n" +
+				"copy files\n" +
+				"\n" +
+				"Arguments:\n" +
+				"    input     source file\n" +
+				"    output    destination file\n" +
+				"    extra     extra files\n" +
+				"\n" +
+				"Flags:\n" +
+				"    --level      \n" +
+				"    --verbose    \n",
+		},
+		{
+			description: "unknown command",
+			name:        "worker",
+			err:         errors.New("unknown command worker, expected one of serve, copy"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			configo := (&Configo{}).
+				AddCommand("serve", "serve requests", &serveConfig{}).
+				AddCommand("copy", "copy files", &copyConfig{})
+
+			var buf bytes.Buffer
+			err := configo.HelpCommand(&buf, testcase.name)
+			if testcase.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, testcase.expected, buf.String())
+			} else {
+				require.EqualError(t, err, testcase.err.Error())
+			}
+		})
+	}
+}
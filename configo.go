@@ -1,9 +1,26 @@
 package configo
 
 import (
+	"reflect"
+
 	"github.com/han0110/configo/node"
 )
 
+// Decoder is implemented by types which decode themselves from a single string.
+type Decoder = node.Decoder
+
+// DecodeHook decodes a single string into value of registered type.
+type DecodeHook = node.DecodeHook
+
+// EncodeHook encodes value of registered type into a single string.
+type EncodeHook = node.EncodeHook
+
+// Validator is implemented by config structs which validate themselves after loading.
+type Validator = node.Validator
+
+// ByteSize represents a size in bytes, which is decoded from string like 512MiB.
+type ByteSize = node.ByteSize
+
 // Default provides a common usage of Configo with env, file, flag loaders.
 func Default() *Configo {
 	return &Configo{
@@ -18,25 +35,71 @@ func Default() *Configo {
 // Configo is the main structure of configo which wraps all utilities for quick usage.
 type Configo struct {
 	Loader         Loader
+	Commands       []*Command
 	TagName        string
 	TagDescription string
+	DecodeHooks    map[reflect.Type]DecodeHook
+	EncodeHooks    map[reflect.Type]EncodeHook
+	// ResponseFiles expands arguments like @args.txt before loading, see
+	// ExpandResponseFiles for the format.
+	ResponseFiles bool
+	// Precedence orders sources from the highest to the lowest, where sources
+	// not listed are lower than listed ones and ordered by Loaders.
+	Precedence []Source
+	// Interpolator resolves references like ${ENV} or ${config.key} inside
+	// values of config keys after merging sources when set, where environment
+	// injected into EnvLoader is used unless it has LookupEnv.
+	Interpolator *Interpolator
+}
+
+// WithPrecedence sets precedence of sources ordered from the highest to the
+// lowest, e.g. WithPrecedence(SourceFlag, SourceEnv, SourceFile, SourceDefault).
+func (configo *Configo) WithPrecedence(sources ...Source) *Configo {
+	configo.Precedence = sources
+	return configo
 }
 
 // Load loads configurations into config, optionally used with arguments to do so.
 func (configo *Configo) Load(config interface{}, args []string) error {
 	// Encode config to node for loaders to load data into it
-	n, err := node.New(config, node.EncoderOption{
-		TagName:        configo.TagName,
-		TagDescription: configo.TagDescription,
-	})
+	n, err := configo.encode(config)
 	if err != nil {
 		return err
 	}
 
-	// Load data into conifg
-	if err := configo.Loader.Load(n, args); err != nil {
+	if args, err = configo.expandArgs(n, args); err != nil {
+		return err
+	}
+
+	return configo.load(n, args)
+}
+
+func (configo *Configo) load(n *node.Node, args []string) error {
+	// Load data of all sources into conifg by precedence
+	if err := loadSources(n, args, Loaders{configo.Loader}, configo.Precedence, configo.Interpolator); err != nil {
+		return err
+	}
+
+	// Validate config and its nested structs
+	if err := n.Validate(); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (configo *Configo) encode(config interface{}) (*node.Node, error) {
+	return node.New(config, node.EncoderOption{
+		TagName:        configo.TagName,
+		TagDescription: configo.TagDescription,
+		DecodeHooks:    configo.DecodeHooks,
+		EncodeHooks:    configo.EncodeHooks,
+	})
+}
+
+func (configo *Configo) expandArgs(n *node.Node, args []string) ([]string, error) {
+	if !configo.ResponseFiles {
+		return args, nil
+	}
+	return ExpandResponseFilesWithNode(args, n)
+}
@@ -0,0 +1,298 @@
+package configo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/han0110/configo/node"
+	"github.com/han0110/configo/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type precedenceConfig struct {
+	Level string `yaml:"level"`
+	Port  int    `yaml:"port"`
+}
+
+func TestLoadPrecedence(t *testing.T) { // nolint: funlen
+	env := MapEnv{"PRECEDENCE_LEVEL": "env", "CONFIG_FILE": "./fixtures/precedence.yaml"}
+
+	testcases := []struct {
+		description string
+		precedence  []Source
+		element     *precedenceConfig
+		args        []string
+		expected    *precedenceConfig
+		err         error
+	}{
+		{
+			description: "order of loaders",
+			element:     &precedenceConfig{},
+			args:        []string{"-f", "./fixtures/precedence.yaml"},
+			expected:    &precedenceConfig{Level: "file", Port: 8080},
+		},
+		{
+			description: "config file from env",
+			precedence:  []Source{SourceEnv},
+			element:     &precedenceConfig{},
+			expected:    &precedenceConfig{Level: "env", Port: 8080},
+		},
+		{
+			description: "profile flag",
+			element:     &precedenceConfig{},
+			args:        []string{"-f", "./fixtures/precedence.yaml", "--profile", "prod"},
+			expected:    &precedenceConfig{Level: "prod", Port: 8080},
+		},
+		{
+			description: "env over file",
+			precedence:  []Source{SourceFlag, SourceEnv, SourceFile},
+			element:     &precedenceConfig{},
+			args:        []string{"-f", "./fixtures/precedence.yaml"},
+			expected:    &precedenceConfig{Level: "env", Port: 8080},
+		},
+		{
+			description: "unlisted sources are lower",
+			precedence:  []Source{SourceEnv},
+			element:     &precedenceConfig{},
+			args:        []string{"-f", "./fixtures/precedence.yaml", "--level", "flag", "--port", "80"},
+			expected:    &precedenceConfig{Level: "env", Port: 80},
+		},
+		{
+			description: "default over file",
+			precedence:  []Source{SourceFlag, SourceDefault, SourceFile, SourceEnv},
+			element:     &precedenceConfig{Port: 443},
+			args:        []string{"-f", "./fixtures/precedence.yaml"},
+			expected:    &precedenceConfig{Level: "file", Port: 443},
+		},
+		{
+			description: "used by higher source",
+			element:     &precedenceConfig{},
+			args:        []string{"-f", "./fixtures/precedence.yaml", "--level", "flag"},
+			expected:    &precedenceConfig{Level: "flag", Port: 8080},
+		},
+		{
+			description: "unused keys of all sources",
+			element:     &precedenceConfig{},
+			args:        []string{"-f", "./fixtures/unused.yaml", "--level", "flag", "--verbose"},
+			err:         errors.New("unused keys unknown; unused flag --verbose"),
+		},
+		{
+			description: "error with source",
+			element:     &precedenceConfig{},
+			args:        []string{"-f", "./fixtures/precedence.yaml", "--port", "http"},
+			err:         errors.New(`load from flag: strconv.ParseInt: parsing "http": invalid syntax`),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			configo := (&Configo{
+				Loader: Loaders{
+					&EnvLoader{Prefix: "PRECEDENCE", Environ: env.Environ},
+					Loaders{
+						&FileLoader{DisallowUnused: true, LookupEnv: env.LookupEnv, ProfileFlag: "profile"},
+						&FlagLoader{DisallowUnused: true, EscapeUnused: []string{"f", "profile"}},
+					},
+				},
+			}).WithPrecedence(testcase.precedence...)
+
+			err := configo.Load(testcase.element, testcase.args)
+			if testcase.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, testcase.expected, testcase.element)
+			} else {
+				require.EqualError(t, err, testcase.err.Error())
+			}
+		})
+	}
+}
+
+type nullConfig struct {
+	Name    string            `yaml:"name"`
+	Hosts   []string          `yaml:"hosts"`
+	Labels  map[string]string `yaml:"labels"`
+	TLS     *nullTLSConfig    `yaml:"tls"`
+	Timeout *int              `yaml:"timeout"`
+	Ports   []int             `yaml:"ports"`
+}
+
+type nullTLSConfig struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+func TestLoadNull(t *testing.T) {
+	timeout := 10
+
+	testcases := []struct {
+		description string
+		args        []string
+		expected    *nullConfig
+	}{
+		{
+			description: "null from file",
+			args:        []string{"-f", "./fixtures/null.yaml"},
+			expected: &nullConfig{
+				Hosts:  []string{},
+				Labels: map[string]string{"a": "1", "c": "3"},
+				Ports:  []int{80},
+			},
+		},
+		{
+			description: "empty flag",
+			args:        []string{"-f", "./fixtures/null.yaml", "--ports="},
+			expected: &nullConfig{
+				Hosts:  []string{},
+				Labels: map[string]string{"a": "1", "c": "3"},
+				Ports:  []int{},
+			},
+		},
+		{
+			description: "empty list flag",
+			args:        []string{"-f", "./fixtures/null.yaml", "--ports", "[]"},
+			expected: &nullConfig{
+				Hosts:  []string{},
+				Labels: map[string]string{"a": "1", "c": "3"},
+				Ports:  []int{},
+			},
+		},
+		{
+			description: "set after null",
+			args:        []string{"-f", "./fixtures/null.yaml", "--tls-cert", "cert.pem", "--hosts-0", "a"},
+			expected: &nullConfig{
+				Hosts:  []string{"a"},
+				Labels: map[string]string{"a": "1", "c": "3"},
+				TLS:    &nullTLSConfig{Cert: "cert.pem"},
+				Ports:  []int{80},
+			},
+		},
+		{
+			description: "empty list and mapping",
+			args:        []string{"-f", "./fixtures/empty.yaml"},
+			expected: &nullConfig{
+				Name:    "default",
+				Hosts:   []string{},
+				Labels:  map[string]string{},
+				TLS:     &nullTLSConfig{Cert: "default.pem", Key: "default.key"},
+				Timeout: &timeout,
+				Ports:   []int{443},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			element := &nullConfig{
+				Name:    "default",
+				Hosts:   []string{"default"},
+				Labels:  map[string]string{"b": "2", "c": "3"},
+				TLS:     &nullTLSConfig{Cert: "default.pem", Key: "default.key"},
+				Timeout: &timeout,
+				Ports:   []int{443},
+			}
+			configo := &Configo{
+				Loader: Loaders{
+					&FileLoader{DisallowUnused: true, LookupEnv: MapEnv{}.LookupEnv},
+					&FlagLoader{DisallowUnused: true},
+				},
+			}
+
+			require.NoError(t, configo.Load(element, testcase.args))
+			assert.Equal(t, testcase.expected, element)
+		})
+	}
+}
+
+type loaderFunc func(n *node.Node, args []string) error
+
+func (f loaderFunc) Load(n *node.Node, args []string) error {
+	return f(n, args)
+}
+
+// setLevel is a loader which is not SourceLoader.
+var setLevel = loaderFunc(func(n *node.Node, _ []string) error {
+	flattenMap := util.NewFlattenMap()
+	flattenMap.Set("level", "custom")
+	return n.FillNode(flattenMap)
+})
+
+func TestLoadCustomLoader(t *testing.T) {
+	testcases := []struct {
+		description string
+		loaders     Loaders
+		args        []string
+		expected    *precedenceConfig
+	}{
+		{
+			description: "between sources",
+			loaders:     Loaders{&FileLoader{}, setLevel, &FlagLoader{}},
+			args:        []string{"-f", "./fixtures/precedence.yaml", "--port", "80"},
+			expected:    &precedenceConfig{Level: "custom", Port: 80},
+		},
+		{
+			description: "overridden by latter source",
+			loaders:     Loaders{&FileLoader{}, setLevel, &FlagLoader{}},
+			args:        []string{"-f", "./fixtures/precedence.yaml", "--level", "flag"},
+			expected:    &precedenceConfig{Level: "flag", Port: 8080},
+		},
+		{
+			description: "first",
+			loaders:     Loaders{setLevel, Loaders{&FileLoader{}}},
+			args:        []string{"-f", "./fixtures/precedence.yaml"},
+			expected:    &precedenceConfig{Level: "file", Port: 8080},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			element := &precedenceConfig{}
+			configo := &Configo{Loader: testcase.loaders}
+
+			require.NoError(t, configo.Load(element, testcase.args))
+			assert.Equal(t, testcase.expected, element)
+		})
+	}
+}
+
+// textOnly implements encoding.TextUnmarshaler without encoding.TextMarshaler.
+type textOnly struct {
+	text string
+}
+
+func (value *textOnly) UnmarshalText(text []byte) error {
+	value.text = "decoded " + string(text)
+	return nil
+}
+
+func TestLoadDefaultNotDecoded(t *testing.T) {
+	element := &struct {
+		Level string   `yaml:"level"`
+		Port  int      `yaml:"port"`
+		Text  textOnly `yaml:"text"`
+	}{Port: 443, Text: textOnly{text: "default"}}
+	configo := (&Configo{
+		Loader: &FileLoader{DisallowUnused: true, LookupEnv: MapEnv{}.LookupEnv},
+	}).WithPrecedence(SourceDefault, SourceFile)
+
+	require.NoError(t, configo.Load(element, []string{"-f", "./fixtures/precedence.yaml"}))
+	assert.Equal(t, textOnly{text: "default"}, element.Text)
+	assert.Equal(t, "file", element.Level)
+	assert.Equal(t, 443, element.Port)
+}
+
+func TestLoadUnusedError(t *testing.T) {
+	configo := &Configo{
+		Loader: Loaders{
+			&FileLoader{DisallowUnused: true, LookupEnv: MapEnv{}.LookupEnv},
+			&FlagLoader{DisallowUnused: true},
+		},
+	}
+
+	err := configo.Load(&precedenceConfig{}, []string{"-f", "./fixtures/unused.yaml", "--verbose"})
+	require.EqualError(t, err, "unused keys unknown; unused flag --verbose")
+
+	var unusedErr *UnusedError
+	require.True(t, errors.As(err, &unusedErr))
+	assert.Len(t, unusedErr.Errors, 2)
+}
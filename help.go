@@ -0,0 +1,40 @@
+package configo
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/han0110/configo/node"
+	"github.com/han0110/configo/util"
+)
+
+var helpTemplate = template.Must(template.New("help").Funcs(template.FuncMap{
+	"flag":    util.ToDashCase,
+	"default": helpDefault,
+	"join":    strings.Join,
+}).Parse(`Flags:
+{{- range . }}
+	--{{ flag .Key }}	{{ .Description }}
+	{{- with .Enum }} (one of: {{ join . ", " }}){{ end }}
+	{{- with default . }} (default: {{ . }}){{ end }}
+{{- end }}
+`))
+
+// Help writes flags of config with their descriptions and default values.
+func (configo *Configo) Help(w io.Writer, config interface{}) error {
+	n, err := configo.encode(config)
+	if err != nil {
+		return err
+	}
+
+	return util.TabExecutor(w, helpTemplate, n.Flat())
+}
+
+func helpDefault(n *node.Node) string {
+	if reflect.Indirect(n.Value).IsZero() {
+		return ""
+	}
+	return n.SerializeValue()
+}
@@ -0,0 +1,58 @@
+package configo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type helpConfig struct {
+	Level   string        `yaml:"level" description:"log level" oneof:"debug,info,warn"`
+	Timeout time.Duration `yaml:"timeout" description:"request timeout"`
+	DB      struct {
+		Host string `yaml:"host" description:"database host"`
+		Port int    `yaml:"port"`
+	} `yaml:"db"`
+}
+
+func TestHelp(t *testing.T) {
+	testcases := []struct {
+		description string
+		element     *helpConfig
+		expected    string
+	}{
+		{
+			description: "without defaults",
+			element:     &helpConfig{},
+			expected: "Flags:\n" +
+				"    --db-host    database host\n" +
+				"    --db-port    \n" +
+				"    --level      log level (one of: debug, info, warn)\n" +
+				"    --timeout    request timeout\n",
+		},
+		{
+			description: "with defaults",
+			element: func() *helpConfig {
+				element := &helpConfig{Level: "info", Timeout: 30 * time.Second}
+				element.DB.Port = 5432
+				return element
+			}(),
+			expected: "Flags:\n" +
+				"    --db-host    database host\n" +
+				"    --db-port     (default: 5432)\n" +
+				"    --level      log level (one of: debug, info, warn) (default: info)\n" +
+				"    --timeout    request timeout (default: 30s)\n",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Default().Help(&buf, testcase.element))
+			assert.Equal(t, testcase.expected, buf.String())
+		})
+	}
+}
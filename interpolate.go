@@ -0,0 +1,180 @@
+package configo
+
+import (
+	"os"
+	"strings"
+
+	"github.com/han0110/configo/node"
+	"github.com/han0110/configo/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	// referenceDefaultSeparator separates name and default value of reference
+	// like ${ENV:-default}.
+	referenceDefaultSeparator = ":-"
+)
+
+// Interpolator resolves references inside values after merging sources, where
+// ${ENV} and ${ENV:-default} refer to environment variables, ${config.key}
+// refers to other key, and $${ escapes ${. Names whose dot-case form is a
+// config key, like ${db.maxConns} or ${http_proxy} of key http.proxy, refer to
+// the key, and others refer to environment variables, except the key's own
+// name like ${PORT} in value of port. References always resolve to original
+// values of keys, so expanded values are never expanded again.
+type Interpolator struct {
+	// LookupEnv retrieves value of environment variable, which defaults to
+	// os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+}
+
+// Interpolate resolves references inside values of map.
+func (interpolator *Interpolator) Interpolate(flattenMap *util.FlattenMap) error {
+	return interpolator.InterpolateWithNode(flattenMap, nil)
+}
+
+// InterpolateWithNode resolves references inside values of map like
+// Interpolate, but only for keys of node, so unrelated values like other
+// environment variables are left as is, and references also resolve to
+// values which node already has.
+func (interpolator *Interpolator) InterpolateWithNode(flattenMap *util.FlattenMap, n *node.Node) error {
+	ip := &interpolation{
+		Interpolator: interpolator,
+		flattenMap:   flattenMap,
+		defaults:     make(map[string]string),
+		resolved:     make(map[string]string),
+		resolving:    make(map[string]bool),
+	}
+	if n != nil {
+		n.FlattenValues(func(key, value string) {
+			ip.defaults[key] = value
+		})
+	}
+	return flattenMap.MapValues(func(key, value string) (string, error) {
+		if n != nil && n.Find(key) == nil {
+			return value, nil
+		}
+		ip.resolving[key] = true
+		defer delete(ip.resolving, key)
+		return ip.expand(key, value)
+	})
+}
+
+type interpolation struct {
+	*Interpolator
+	flattenMap *util.FlattenMap
+	defaults   map[string]string
+	resolved   map[string]string
+	resolving  map[string]bool
+}
+
+// expand replaces references inside value of key.
+func (ip *interpolation) expand(key, value string) (string, error) {
+	var builder strings.Builder
+	for i := 0; i < len(value); {
+		switch {
+		case strings.HasPrefix(value[i:], "$${"):
+			builder.WriteString("${")
+			i += 3
+		case strings.HasPrefix(value[i:], "${"):
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				return "", errors.Errorf("unclosed reference in value of %s", key)
+			}
+			resolved, err := ip.reference(key, value[i+2:i+2+end])
+			if err != nil {
+				return "", err
+			}
+			builder.WriteString(resolved)
+			i += end + 3
+		default:
+			builder.WriteByte(value[i])
+			i++
+		}
+	}
+	return builder.String(), nil
+}
+
+// reference resolves reference like name or name:-default inside value of key,
+// where default is used when name is unset or empty.
+func (ip *interpolation) reference(key, reference string) (string, error) {
+	name, defaultValue, hasDefault := reference, "", false
+	if i := strings.Index(reference, referenceDefaultSeparator); i >= 0 {
+		name, defaultValue, hasDefault = reference[:i], reference[i+len(referenceDefaultSeparator):], true
+	}
+
+	var value string
+	var ok bool
+	if util.ToDotCase(name) != key {
+		var err error
+		if value, ok, err = ip.resolve(key, name); err != nil {
+			return "", err
+		}
+	}
+	if !ok {
+		value, ok = ip.lookupEnv(name)
+	}
+
+	switch {
+	case ok && value != "":
+		return value, nil
+	case hasDefault:
+		return defaultValue, nil
+	case ok:
+		return value, nil
+	default:
+		return "", errors.Errorf("undefined reference ${%s} in value of %s", name, key)
+	}
+}
+
+// resolve returns interpolated value of key referred by name inside value of
+// referrer, which prefers value set with segments split from name by dot like
+// filling node does, and falls back to value which node already has.
+func (ip *interpolation) resolve(referrer, name string) (string, bool, error) {
+	if value, ok := ip.resolved[name]; ok {
+		return value, true, nil
+	}
+	segments := strings.Split(name, util.CharDot)
+	for i := range segments {
+		segments[i] = util.ToDotCase(segments[i])
+	}
+	key := strings.Join(segments, util.CharDot)
+	value, ok := ip.flattenMap.SegmentedLookup(key, segments)
+	if !ok {
+		value, ok = ip.defaults[key]
+		return value, ok, nil
+	}
+	if ip.resolving[key] {
+		return "", false, errors.Errorf("cyclic reference ${%s} in value of %s", name, referrer)
+	}
+
+	ip.resolving[key] = true
+	defer delete(ip.resolving, key)
+	value, err := ip.expand(key, value)
+	if err != nil {
+		return "", false, err
+	}
+	ip.resolved[name] = value
+	return value, true, nil
+}
+
+func (ip *interpolation) lookupEnv(key string) (string, bool) {
+	if ip.LookupEnv == nil {
+		return os.LookupEnv(key)
+	}
+	return ip.LookupEnv(key)
+}
+
+// withEnviron returns interpolator looking up environment injected into
+// EnvLoader.Environ of loaders, when LookupEnv is not set.
+func (interpolator *Interpolator) withEnviron(loaders []SourceLoader) *Interpolator {
+	if interpolator.LookupEnv != nil {
+		return interpolator
+	}
+	for _, loader := range loaders {
+		if envLoader, ok := loader.(*EnvLoader); ok && envLoader.Environ != nil {
+			return &Interpolator{LookupEnv: NewMapEnv(envLoader.Environ()).LookupEnv}
+		}
+	}
+	return interpolator
+}
@@ -0,0 +1,158 @@
+package configo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/han0110/configo/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolate(t *testing.T) { // nolint: funlen
+	env := MapEnv{"DB_USER": "admin", "EMPTY": "", "PORT": "9090", "http_proxy": "http://proxy"}
+
+	testcases := []struct {
+		description string
+		keyValues   [][2]string
+		expected    []string
+		err         error
+	}{
+		{
+			description: "env and config keys",
+			keyValues: [][2]string{
+				{"url", "postgres://${DB_USER}@${db.host}:${db.port}/app"},
+				{"db.host", "${HOST:-localhost}"},
+				{"db.port", "5432"},
+			},
+			expected: []string{"postgres://admin@localhost:5432/app", "localhost", "5432"},
+		},
+		{
+			description: "default of empty value",
+			keyValues:   [][2]string{{"name", "${EMPTY:-app}-${missing:-1}"}, {"empty", "${EMPTY}"}},
+			expected:    []string{"app-1", ""},
+		},
+		{
+			description: "escaped reference",
+			keyValues:   [][2]string{{"template", "$${name} costs $5"}},
+			expected:    []string{"${name} costs $5"},
+		},
+		{
+			description: "reference to escaped reference",
+			keyValues:   [][2]string{{"a", "$${x}"}, {"b", "${a}"}},
+			expected:    []string{"${x}", "${x}"},
+		},
+		{
+			description: "lowercase env",
+			keyValues:   [][2]string{{"proxy", "${http_proxy}"}},
+			expected:    []string{"http://proxy"},
+		},
+		{
+			description: "config key over lowercase env",
+			keyValues:   [][2]string{{"http.proxy", "http://config"}, {"proxy", "${http_proxy}"}},
+			expected:    []string{"http://config", "http://config"},
+		},
+		{
+			description: "camel case config key",
+			keyValues:   [][2]string{{"db.max.conns", "10"}, {"db.url", "x:${db.maxConns}"}},
+			expected:    []string{"10", "x:10"},
+		},
+		{
+			description: "env of own key",
+			keyValues:   [][2]string{{"port", "${PORT:-8080}"}},
+			expected:    []string{"9090"},
+		},
+		{
+			description: "undefined reference",
+			keyValues:   [][2]string{{"url", "${db.host}"}},
+			err:         errors.New("undefined reference ${db.host} in value of url"),
+		},
+		{
+			description: "cyclic reference",
+			keyValues:   [][2]string{{"a", "${b}"}, {"b", "x${a}"}},
+			err:         errors.New("cyclic reference ${a} in value of b"),
+		},
+		{
+			description: "unclosed reference",
+			keyValues:   [][2]string{{"a", "${b"}},
+			err:         errors.New("unclosed reference in value of a"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			flattenMap := util.NewFlattenMap()
+			for _, keyValue := range testcase.keyValues {
+				flattenMap.Set(keyValue[0], keyValue[1])
+			}
+
+			err := (&Interpolator{LookupEnv: env.LookupEnv}).Interpolate(flattenMap)
+			if testcase.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, testcase.expected, flattenMap.Values())
+			} else {
+				require.EqualError(t, err, testcase.err.Error())
+			}
+		})
+	}
+}
+
+func TestInterpolateSegments(t *testing.T) {
+	env := MapEnv{
+		"MAX_CONNS":     "1",
+		"MAX__CONNS":    "2",
+		"POOLS__MAIN":   "${max.conns}",
+		"POOLS__BACKUP": "${max_conns}",
+	}
+	configo := &Configo{
+		Loader: &EnvLoader{
+			DisallowUnused:   true,
+			NestingSeparator: "__",
+			Environ:          env.Environ,
+		},
+		Interpolator: &Interpolator{LookupEnv: MapEnv{}.LookupEnv},
+	}
+
+	var config collisionConfig
+	require.NoError(t, configo.Load(&config, nil))
+	var expected collisionConfig
+	expected.MaxConns = 1
+	expected.Max.Conns = 2
+	expected.Pools = map[string]int{"main": 2, "backup": 1}
+	assert.Equal(t, expected, config)
+}
+
+type interpolationConfig struct {
+	URL  string `yaml:"url"`
+	User string `yaml:"user"`
+	DB   struct {
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		MaxConns int    `yaml:"maxConns"`
+	} `yaml:"db"`
+}
+
+func TestLoadInterpolation(t *testing.T) {
+	env := MapEnv{
+		"PS1":      `${debian_chroot:+($debian_chroot)}\u@\h`,
+		"DB_HOST":  "localhost",
+		"APP_USER": "admin",
+	}
+	configo := &Configo{
+		Loader: Loaders{
+			&EnvLoader{Environ: env.Environ},
+			&FlagLoader{DisallowUnused: true},
+		},
+		Interpolator: &Interpolator{},
+	}
+
+	var config interpolationConfig
+	config.DB.Port = 5432
+	require.NoError(t, configo.Load(&config, []string{
+		"--url", "postgres://${user}@${db.host}:${db.port}/app?max=${db.maxConns}",
+		"--user", "${APP_USER}",
+		"--db-max-conns", "10",
+	}))
+	assert.Equal(t, "postgres://admin@localhost:5432/app?max=10", config.URL)
+	assert.Equal(t, "admin", config.User)
+}
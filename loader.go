@@ -1,7 +1,26 @@
 package configo
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/han0110/configo/node"
+	"github.com/han0110/configo/util"
+	"github.com/pkg/errors"
+)
+
+// Source identifies where configurations are loaded from.
+type Source string
+
+const (
+	// SourceFlag is source of FlagLoader.
+	SourceFlag Source = "flag"
+	// SourceEnv is source of EnvLoader.
+	SourceEnv Source = "env"
+	// SourceFile is source of FileLoader.
+	SourceFile Source = "file"
+	// SourceDefault is source of values which config already has before loading.
+	SourceDefault Source = "default"
 )
 
 // Loader is a configuration store.
@@ -10,17 +29,215 @@ type Loader interface {
 	Load(n *node.Node, args []string) error
 }
 
+// SourceLoader is a Loader which parses its source into a map without filling
+// node, so maps of multiple sources could be merged by precedence and filled
+// into node once.
+type SourceLoader interface {
+	Loader
+	// Source returns source of the loader.
+	Source() Source
+	// Parse parses data of source into a map, optionally used with arguments
+	// to do so.
+	Parse(n *node.Node, args []string) (*util.FlattenMap, error)
+	// CheckUnused checks unused keys of map returned by Parse after filling.
+	CheckUnused(n *node.Node, flattenMap *util.FlattenMap) error
+}
+
 // Loaders wraps multiple Loaders as Loader interface
 type Loaders []Loader
 
 var _ Loader = (Loaders)(nil)
 
-// Load implements Loader
+// Load implements Loader, which merges sources of loaders with later one
+// overriding former and fills node once, see loadSources.
 func (loaders Loaders) Load(n *node.Node, args []string) error {
+	return loadSources(n, args, loaders, nil, nil)
+}
+
+// UnusedError describes unused keys of sources, which keeps error of each
+// source to be matched by errors.Is or errors.As.
+type UnusedError struct {
+	Errors []error
+}
+
+// Error implements error.
+func (err *UnusedError) Error() string {
+	msgs := make([]string, len(err.Errors))
+	for i := range err.Errors {
+		msgs[i] = err.Errors[i].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether error of any source matches target.
+func (err *UnusedError) Is(target error) bool {
+	for _, sourceErr := range err.Errors {
+		if errors.Is(sourceErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of sources which matches target.
+func (err *UnusedError) As(target interface{}) bool {
+	for _, sourceErr := range err.Errors {
+		if errors.As(sourceErr, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type parsedSource struct {
+	loader     SourceLoader
+	source     Source
+	rank       int
+	flattenMap *util.FlattenMap
+}
+
+// loadSources loads nested loaders in declared order, where consecutive
+// SourceLoaders are loaded together by loadSourceLoaders, and other loaders
+// fill node by themselves between them, so they still override former loaders
+// and are overridden by latter ones.
+func loadSources(
+	n *node.Node, args []string, loaders Loaders, precedence []Source, interpolator *Interpolator,
+) error {
+	var group []SourceLoader
+	withDefault := true
+	loadGroup := func() error {
+		if len(group) == 0 {
+			return nil
+		}
+		err := loadSourceLoaders(n, args, group, precedence, interpolator, withDefault)
+		group, withDefault = nil, false
+		return err
+	}
+
+	var load func(loaders Loaders) error
+	load = func(loaders Loaders) error {
+		for _, loader := range loaders {
+			switch loader := loader.(type) {
+			case Loaders:
+				if err := load(loader); err != nil {
+					return err
+				}
+			case SourceLoader:
+				group = append(group, loader)
+			default:
+				if err := loadGroup(); err != nil {
+					return err
+				}
+				if err := loader.Load(n, args); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := load(loaders); err != nil {
+		return err
+	}
+	return loadGroup()
+}
+
+// loadSourceLoaders parses sources of loaders into maps, merges them by
+// precedence ordered from highest to lowest, optionally interpolates, and
+// fills node once, where sources not in precedence are lower than those in it
+// and later loader overrides former. With SourceDefault in precedence, values
+// which config has before loading are kept when they are higher.
+func loadSourceLoaders(
+	n *node.Node,
+	args []string,
+	loaders []SourceLoader,
+	precedence []Source,
+	interpolator *Interpolator,
+	withDefault bool,
+) error {
+	rank := func(source Source) int {
+		for i := range precedence {
+			if precedence[i] == source {
+				return i
+			}
+		}
+		return len(precedence)
+	}
+
+	var sources []*parsedSource
 	for _, loader := range loaders {
-		if err := loader.Load(n, args); err != nil {
+		flattenMap, err := loader.Parse(n, args)
+		if err != nil {
 			return err
 		}
+		sources = append(sources, &parsedSource{
+			loader:     loader,
+			source:     loader.Source(),
+			rank:       rank(loader.Source()),
+			flattenMap: flattenMap,
+		})
 	}
+
+	// Values which config already has are only needed to override lower ones
+	if rank := rank(SourceDefault); withDefault && rank < len(precedence) {
+		flattenMap := util.NewFlattenMap()
+		n.FlattenValues(func(key, value string) {
+			flattenMap.Set(key, value)
+		})
+		sources = append(sources, &parsedSource{
+			source:     SourceDefault,
+			rank:       rank,
+			flattenMap: flattenMap,
+		})
+	}
+
+	// Merge from the lowest to the highest
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].rank > sources[j].rank })
+	merged := util.NewFlattenMap()
+	for _, source := range sources {
+		merged.Merge(source.flattenMap, string(source.source))
+	}
+
+	// Resolve references inside values
+	if interpolator != nil {
+		if err := interpolator.withEnviron(loaders).InterpolateWithNode(merged, n); err != nil {
+			return err
+		}
+	}
+
+	// Keep values which config already has instead of decoding them back
+	for _, key := range merged.Keys() {
+		if merged.Source(key) == string(SourceDefault) {
+			merged.Delete(key)
+		}
+	}
+
+	// Fill data into node
+	if err := n.FillNode(merged); err != nil {
+		var fillErr *node.FillError
+		if errors.As(err, &fillErr) {
+			if source := merged.Source(fillErr.Key); source != "" {
+				return errors.Wrapf(err, "load from %s", source)
+			}
+		}
+		return err
+	}
+
+	// Check whether there are unused keys of each source against the merged
+	// map, and report them all at once
+	var unused []error
+	for _, source := range sources {
+		if source.loader == nil {
+			continue
+		}
+		source.flattenMap.MarkUsed(merged)
+		if err := source.loader.CheckUnused(n, source.flattenMap); err != nil {
+			unused = append(unused, err)
+		}
+	}
+	if len(unused) > 0 {
+		return &UnusedError{Errors: unused}
+	}
+
 	return nil
 }
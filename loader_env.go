@@ -2,6 +2,7 @@ package configo
 
 import (
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/han0110/configo/node"
@@ -13,41 +14,252 @@ import (
 type EnvLoader struct {
 	Prefix         string
 	DisallowUnused bool
+	// Prefixes are fallback prefixes after Prefix, where variable with the
+	// first matching prefix wins.
+	Prefixes []string
+	// OnFallbackPrefix is called with name of variable used with fallback
+	// prefix and its preferred name with the first prefix, e.g. to warn about
+	// deprecation.
+	OnFallbackPrefix func(name, preferred string)
+	// EscapeUnused lists names of environment variables which are never
+	// reported as unused.
+	EscapeUnused []string
+	// Environ returns environment variables in the form key=value, which
+	// defaults to os.Environ.
+	Environ func() []string
+	// NestingSeparator separates names of nested keys strictly, e.g. "__" for
+	// MAX__CONNS to be max.conns and MAX_CONNS to be max_conns. When it's not
+	// set, names are resolved by matching keys of node.
+	NestingSeparator string
 }
 
-var _ Loader = (*EnvLoader)(nil)
+var _ SourceLoader = (*EnvLoader)(nil)
 
 // Load implements ConfigLoader.
 func (loader *EnvLoader) Load(n *node.Node, args []string) error {
-	if loader.Prefix != "" {
-		loader.Prefix += util.CharUnderscore
-	}
+	return loadSources(n, args, Loaders{loader}, nil, nil)
+}
+
+// Source implements SourceLoader.
+func (loader *EnvLoader) Source() Source {
+	return SourceEnv
+}
+
+// Parse implements SourceLoader.
+func (loader *EnvLoader) Parse(n *node.Node, args []string) (*util.FlattenMap, error) {
+	prefixes := loader.prefixes()
+	environ := loader.environ()
 
+	// Set from the last prefix to the first, so the first matching one wins
 	flattenMap := util.NewFlattenMap()
-	for _, env := range os.Environ() {
-		key := strings.SplitN(env, "=", 2)[0]
-		if !strings.HasPrefix(env, loader.Prefix) {
-			continue
+	for i := len(prefixes) - 1; i >= 0; i-- {
+		for _, env := range environ {
+			keyValue := strings.SplitN(env, "=", 2)
+			if len(keyValue) != 2 || !strings.HasPrefix(keyValue[0], prefixes[i]) {
+				continue
+			}
+			key := keyValue[0][len(prefixes[i]):]
+			segments, err := loader.segments(n, keyValue[0], key)
+			if err != nil {
+				return nil, err
+			}
+			if segments != nil {
+				flattenMap.SetWithSegments(key, keyValue[1], segments)
+			} else {
+				flattenMap.Set(key, keyValue[1])
+			}
 		}
-		flattenMap.Set(key[len(loader.Prefix):], os.Getenv(key))
 	}
 
-	// Fill data into node
-	if err := n.FillNode(flattenMap); err != nil {
-		return err
+	return flattenMap, nil
+}
+
+// CheckUnused implements SourceLoader, which calls OnFallbackPrefix for used
+// variables with fallback prefix, and only reports variables sharing the
+// prefix when it is set, otherwise variables sharing the first segment of
+// nested keys or looking like near-misses of keys, since the environment is
+// full of unrelated variables.
+func (loader *EnvLoader) CheckUnused(n *node.Node, flattenMap *util.FlattenMap) error {
+	prefixes := loader.prefixes()
+	names := make(map[string]bool)
+	for _, env := range loader.environ() {
+		names[strings.SplitN(env, "=", 2)[0]] = true
+	}
+	loader.notifyFallbacks(names, flattenMap.UnusedKeys(nil))
+
+	if !loader.DisallowUnused {
+		return nil
 	}
 
-	// Check whether there are unused keys
-	if loader.DisallowUnused {
-		if unusedKeys := flattenMap.UnusedKeys(nil); len(unusedKeys) > 0 {
-			if loader.Prefix != "" {
-				for i := range unusedKeys {
-					unusedKeys[i] = loader.Prefix + unusedKeys[i]
-				}
+	escaped := util.StringsToSet(loader.EscapeUnused)
+	unprefixed := prefixes[0] == ""
+	var candidates []string
+	if unprefixed {
+		candidates = envCandidates(n)
+	}
+
+	var unusedKeys []string
+	for _, key := range flattenMap.UnusedKeys(nil) {
+		name := key
+		for _, prefix := range prefixes {
+			if names[prefix+key] {
+				name = prefix + key
+				break
 			}
-			return errors.Errorf("unused env %s", strings.Join(unusedKeys, ", "))
 		}
+		if escaped[name] || (unprefixed && !isRelatedEnv(key, candidates)) {
+			continue
+		}
+		unusedKeys = append(unusedKeys, name)
+	}
+	if len(unusedKeys) > 0 {
+		return errors.Errorf("unused env %s", strings.Join(unusedKeys, ", "))
 	}
 
 	return nil
 }
+
+// notifyFallbacks calls OnFallbackPrefix in order by name for variables with
+// fallback prefix, which are used and not overridden by former prefixes.
+func (loader *EnvLoader) notifyFallbacks(names map[string]bool, unusedKeys []string) {
+	prefixes := loader.prefixes()
+	if loader.OnFallbackPrefix == nil || len(prefixes) < 2 {
+		return
+	}
+
+	unused := util.StringsToSet(unusedKeys)
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		for i := 1; i < len(prefixes); i++ {
+			if !strings.HasPrefix(name, prefixes[i]) {
+				continue
+			}
+			key := name[len(prefixes[i]):]
+			if unused[key] || hasEnv(names, prefixes[:i], key) {
+				break
+			}
+			loader.OnFallbackPrefix(name, prefixes[0]+key)
+			break
+		}
+	}
+}
+
+// hasEnv checks whether variable of key with any of prefixes exists.
+func hasEnv(names map[string]bool, prefixes []string, key string) bool {
+	for _, prefix := range prefixes {
+		if names[prefix+key] {
+			return true
+		}
+	}
+	return false
+}
+
+// envCandidates returns names of environment variables in screaming case
+// which could be used by node.
+func envCandidates(n *node.Node) (candidates []string) {
+	_ = n.Walk(func(child *node.Node) error {
+		if child.Key != "" {
+			candidates = append(candidates, util.ToScreamingCase(child.Key))
+		}
+		return nil
+	})
+	return candidates
+}
+
+// isRelatedEnv checks whether name shares the first segment with a nested
+// candidate, or is within edit distance of a quarter of candidate's length.
+func isRelatedEnv(name string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if segments := strings.SplitN(candidate, util.CharUnderscore, 2); len(segments) == 2 &&
+			strings.HasPrefix(name, segments[0]+util.CharUnderscore) {
+			return true
+		}
+		if distance := util.EditDistance(name, candidate); distance <= len(candidate)/4 {
+			return true
+		}
+	}
+	return false
+}
+
+// segments returns segments of key split by NestingSeparator, otherwise nil
+// when key matches at most one node, or error when name is ambiguous.
+func (loader *EnvLoader) segments(n *node.Node, name, key string) ([]string, error) {
+	if loader.NestingSeparator != "" {
+		segments := strings.Split(key, loader.NestingSeparator)
+		for i := range segments {
+			segments[i] = util.ToDotCase(segments[i])
+		}
+		return segments, nil
+	}
+
+	matches := n.FindAll(util.ToDotCase(key))
+	switch len(matches) {
+	case 0, 1:
+		return nil, nil
+	default:
+		keys := make([]string, len(matches))
+		for i, match := range matches {
+			keys[i] = strings.Join(match.Segments, util.CharDot)
+		}
+		return nil, errors.Errorf("ambiguous env %s, which matches %s", name, strings.Join(keys, " and "))
+	}
+}
+
+// prefixes returns Prefix and Prefixes joined with underscore, or only an
+// empty prefix when none is set.
+func (loader *EnvLoader) prefixes() []string {
+	var prefixes []string
+	for _, prefix := range append([]string{loader.Prefix}, loader.Prefixes...) {
+		if prefix != "" {
+			prefixes = append(prefixes, prefix+util.CharUnderscore)
+		}
+	}
+	if len(prefixes) == 0 {
+		return []string{""}
+	}
+	return prefixes
+}
+
+func (loader *EnvLoader) environ() []string {
+	if loader.Environ == nil {
+		return os.Environ()
+	}
+	return loader.Environ()
+}
+
+// MapEnv is a captured environment, which could be used as EnvLoader.Environ
+// and FileLoader.LookupEnv to load from environment other than the process's.
+type MapEnv map[string]string
+
+// NewMapEnv captures environment variables in the form key=value, like
+// os.Environ or content of /proc/<pid>/environ split by NUL.
+func NewMapEnv(environ []string) MapEnv {
+	env := make(MapEnv, len(environ))
+	for _, keyValue := range environ {
+		if pair := strings.SplitN(keyValue, "=", 2); len(pair) == 2 && pair[0] != "" {
+			env[pair[0]] = pair[1]
+		}
+	}
+	return env
+}
+
+// Environ returns environment variables in the form key=value sorted by key.
+func (env MapEnv) Environ() []string {
+	environ := make([]string, 0, len(env))
+	for key, value := range env {
+		environ = append(environ, key+"="+value)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// LookupEnv retrieves value of environment variable named by key.
+func (env MapEnv) LookupEnv(key string) (string, bool) {
+	value, ok := env[key]
+	return value, ok
+}
@@ -0,0 +1,187 @@
+package configo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type envConfig struct {
+	LogLevel string `yaml:"log_level"`
+	DB       struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	} `yaml:"db"`
+}
+
+func TestEnvLoader(t *testing.T) { // nolint: funlen
+	testcases := []struct {
+		description string
+		loader      *EnvLoader
+		env         map[string]string
+		expected    func(*envConfig)
+		fallbacks   [][2]string
+		err         error
+	}{
+		{
+			description: "unrelated env",
+			loader:      &EnvLoader{DisallowUnused: true},
+			env:         map[string]string{"LOG_LEVEL": "debug", "EDITOR": "vim"},
+			expected:    func(config *envConfig) { config.LogLevel = "debug" },
+		},
+		{
+			description: "near-miss env",
+			loader:      &EnvLoader{DisallowUnused: true},
+			env:         map[string]string{"LOG_LEVLE": "debug"},
+			err:         errors.New("unused env LOG_LEVLE"),
+		},
+		{
+			description: "env sharing segment of nested key",
+			loader:      &EnvLoader{DisallowUnused: true},
+			env:         map[string]string{"DB_HOST": "localhost", "DB_HOSTNAME": "localhost"},
+			err:         errors.New("unused env DB_HOSTNAME"),
+		},
+		{
+			description: "escaped env",
+			loader:      &EnvLoader{DisallowUnused: true, EscapeUnused: []string{"DB_HOSTNAME"}},
+			env:         map[string]string{"DB_PORT": "5432", "DB_HOSTNAME": "localhost"},
+			expected:    func(config *envConfig) { config.DB.Port = 5432 },
+		},
+		{
+			description: "env with prefix",
+			loader:      &EnvLoader{Prefix: "APP", DisallowUnused: true},
+			env:         map[string]string{"APP_DB_HOST": "localhost", "APP_EDITOR": "vim"},
+			err:         errors.New("unused env APP_EDITOR"),
+		},
+		{
+			description: "env with fallback prefix",
+			loader:      &EnvLoader{Prefix: "NEWAPP", Prefixes: []string{"OLDAPP"}},
+			env: map[string]string{
+				"NEWAPP_DB_HOST": "new",
+				"OLDAPP_DB_HOST": "old",
+				"OLDAPP_DB_PORT": "5432",
+				"OLDAPP_EDITOR":  "vim",
+			},
+			expected: func(config *envConfig) {
+				config.DB.Host = "new"
+				config.DB.Port = 5432
+			},
+			fallbacks: [][2]string{{"OLDAPP_DB_PORT", "NEWAPP_DB_PORT"}},
+		},
+		{
+			description: "unused env with fallback prefix",
+			loader:      &EnvLoader{Prefixes: []string{"NEWAPP", "OLDAPP"}, DisallowUnused: true},
+			env:         map[string]string{"NEWAPP_DB_HOST": "new", "OLDAPP_DB_NAME": "app"},
+			err:         errors.New("unused env OLDAPP_DB_NAME"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			var fallbacks [][2]string
+			testcase.loader.OnFallbackPrefix = func(name, preferred string) {
+				fallbacks = append(fallbacks, [2]string{name, preferred})
+			}
+			testcase.loader.Environ = MapEnv(testcase.env).Environ
+
+			var config envConfig
+			err := (&Configo{Loader: testcase.loader}).Load(&config, nil)
+			if testcase.err == nil {
+				require.NoError(t, err)
+				var expected envConfig
+				testcase.expected(&expected)
+				assert.Equal(t, expected, config)
+				assert.Equal(t, testcase.fallbacks, fallbacks)
+			} else {
+				require.EqualError(t, err, testcase.err.Error())
+			}
+		})
+	}
+}
+
+func TestMapEnv(t *testing.T) {
+	env := NewMapEnv([]string{"FOO=bar", "EMPTY=", "EQUAL=a=b", "INVALID", "=value"})
+	assert.Equal(t, []string{"EMPTY=", "EQUAL=a=b", "FOO=bar"}, env.Environ())
+
+	value, ok := env.LookupEnv("EQUAL")
+	assert.True(t, ok)
+	assert.Equal(t, "a=b", value)
+	_, ok = env.LookupEnv("INVALID")
+	assert.False(t, ok)
+}
+
+type collisionConfig struct {
+	MaxConns int `yaml:"max_conns"`
+	Max      struct {
+		Conns int `yaml:"conns"`
+	} `yaml:"max"`
+	Pools map[string]int `yaml:"pools"`
+}
+
+func TestEnvLoaderSegments(t *testing.T) {
+	testcases := []struct {
+		description string
+		separator   string
+		env         map[string]string
+		expected    func(*collisionConfig)
+		err         error
+	}{
+		{
+			description: "ambiguous env",
+			env:         map[string]string{"MAX_CONNS": "1"},
+			err:         errors.New("ambiguous env MAX_CONNS, which matches max_conns and max.conns"),
+		},
+		{
+			description: "resolved env",
+			env:         map[string]string{"POOLS_MAIN": "3"},
+			expected:    func(config *collisionConfig) { config.Pools = map[string]int{"main": 3} },
+		},
+		{
+			description: "nesting separator",
+			separator:   "__",
+			env:         map[string]string{"MAX_CONNS": "1", "MAX__CONNS": "2", "POOLS__MAIN": "3"},
+			expected: func(config *collisionConfig) {
+				config.MaxConns = 1
+				config.Max.Conns = 2
+				config.Pools = map[string]int{"main": 3}
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			loader := &EnvLoader{
+				DisallowUnused:   true,
+				NestingSeparator: testcase.separator,
+				Environ:          MapEnv(testcase.env).Environ,
+			}
+
+			var config collisionConfig
+			err := (&Configo{Loader: loader}).Load(&config, nil)
+			if testcase.err == nil {
+				require.NoError(t, err)
+				var expected collisionConfig
+				testcase.expected(&expected)
+				assert.Equal(t, expected, config)
+			} else {
+				require.EqualError(t, err, testcase.err.Error())
+			}
+		})
+	}
+}
+
+func TestEnvLoaderSegmentsOverrideFile(t *testing.T) {
+	env := MapEnv{"APP_LEVEL": "env"}
+	configo := &Configo{
+		Loader: Loaders{
+			&FileLoader{DisallowUnused: true, LookupEnv: MapEnv{}.LookupEnv},
+			&EnvLoader{Prefix: "APP", DisallowUnused: true, NestingSeparator: "__", Environ: env.Environ},
+		},
+	}
+
+	var config precedenceConfig
+	require.NoError(t, configo.Load(&config, []string{"-f", "./fixtures/precedence.yaml"}))
+	assert.Equal(t, precedenceConfig{Level: "env", Port: 8080}, config)
+}
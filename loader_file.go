@@ -1,10 +1,13 @@
 package configo
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/han0110/configo/node"
+	"github.com/han0110/configo/util"
 	"github.com/pkg/errors"
 )
 
@@ -15,17 +18,70 @@ const (
 	defaultConfigFileEnv = "CONFIG_FILE"
 )
 
-// FileLoader loads config from file.
+// FileLoader loads config from file, or supported files in directory, where
+// paths given by flag or env are separated by comma and could be glob
+// patterns.
 type FileLoader struct {
 	DisallowUnused bool
 	ConfigFileFlag string
 	ConfigFileEnv  string
+	// LookupEnv retrieves value of environment variable, which defaults to
+	// os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+	// SearchPaths are config file or directory paths ordered from the highest
+	// priority to the lowest, which are searched when no path is given by flag
+	// or env.
+	// Environment variables like $XDG_CONFIG_HOME are expanded, and paths with
+	// unset variables are skipped.
+	SearchPaths []string
+	// SearchAll loads all found files of SearchPaths from the lowest priority
+	// to the highest, instead of only the first found one.
+	SearchAll bool
+	// DisallowEmptyGlob returns error when a glob pattern like *.yaml or
+	// **/*.yml in config file paths matches nothing.
+	DisallowEmptyGlob bool
+	// Optional are config file paths loaded after others when they exist,
+	// like a local override file. Paths prefixed by ? are also optional.
+	Optional []string
+	// ProfileFlag and ProfileEnv opt in comma separated active profiles like
+	// --profile or APP_PROFILE, which overlay config.yaml by
+	// config.<profile>.yaml. ProfileFlag should also be in
+	// FlagLoader.EscapeUnused when it disallows unused flags. Overlay files are
+	// skipped when loading config directory or glob pattern.
+	ProfileFlag string
+	ProfileEnv  string
+	// ProfilesKey opts in sections of active profiles in file like
+	// profiles.<profile>, which are merged after keys of file. Sections are
+	// not recognized when it's empty.
+	ProfilesKey string
 }
 
-var _ Loader = (*FileLoader)(nil)
+// DefaultSearchPaths returns common config file paths of app, which are
+// ./config.yaml, $XDG_CONFIG_HOME/<app>/config.yaml,
+// $HOME/.config/<app>/config.yaml and /etc/<app>/config.yaml.
+func DefaultSearchPaths(app string) []string {
+	return []string{
+		filepath.Join(".", "config.yaml"),
+		filepath.Join("$XDG_CONFIG_HOME", app, "config.yaml"),
+		filepath.Join("$HOME", ".config", app, "config.yaml"),
+		filepath.Join("/etc", app, "config.yaml"),
+	}
+}
+
+var _ SourceLoader = (*FileLoader)(nil)
 
 // Load implements ConfigLoader.
 func (loader *FileLoader) Load(n *node.Node, args []string) error {
+	return loadSources(n, args, Loaders{loader}, nil, nil)
+}
+
+// Source implements SourceLoader.
+func (loader *FileLoader) Source() Source {
+	return SourceFile
+}
+
+// Parse implements SourceLoader.
+func (loader *FileLoader) Parse(n *node.Node, args []string) (*util.FlattenMap, error) {
 	if loader.ConfigFileFlag == "" {
 		loader.ConfigFileFlag = defaultConfigFileFlag
 	}
@@ -34,47 +90,196 @@ func (loader *FileLoader) Load(n *node.Node, args []string) error {
 	}
 
 	// Find config filepaths from flags and environments.
-	filepaths := loader.findConfigFilePaths(args)
-
-	// Parse files into map[string]string.
-	flattenMap, err := ParseFile(filepaths)
+	filepaths, err := loader.expandGlobs(loader.findConfigFilePaths(n, args))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if filepaths, err = expandConfigDirs(filepaths); err != nil {
+		return nil, err
 	}
 
-	// Fill data into node
-	if err := n.FillNode(flattenMap); err != nil {
-		return err
+	// Parse files into map[string]string.
+	return ParseFileWithProfiles(filepaths, loader.findProfiles(n, args))
+}
+
+// CheckUnused implements SourceLoader.
+func (loader *FileLoader) CheckUnused(n *node.Node, flattenMap *util.FlattenMap) error {
+	if !loader.DisallowUnused {
+		return nil
 	}
 
-	// Check whether there are unused keys
-	if loader.DisallowUnused {
-		if unusedKeys := flattenMap.UnusedKeys(nil); len(unusedKeys) > 0 {
-			return errors.Errorf("unused keys %s", strings.Join(unusedKeys, ", "))
-		}
+	if unusedKeys := flattenMap.UnusedKeys(nil); len(unusedKeys) > 0 {
+		return errors.Errorf("unused keys %s", strings.Join(unusedKeys, ", "))
 	}
 
 	return nil
 }
 
-func (loader *FileLoader) findConfigFilePaths(args []string) []string {
+func (loader *FileLoader) findConfigFilePaths(n *node.Node, args []string) []string {
 	var filepaths []string
 
 	// Find config file from flags.
 	if loader.ConfigFileFlag != "-" {
-		if flattenMap, err := ParseFlag(args); err == nil {
+		if flattenMap, _, err := ParseFlagWithNode(args, n); err == nil {
 			if value, ok := flattenMap.Value(loader.ConfigFileFlag); ok && value != "" {
-				filepaths = append(filepaths, strings.Split(value, ",")...)
+				filepaths = append(filepaths, splitConfigFilePaths(value)...)
 			}
 		}
 	}
 
 	// Find config file from environments.
 	if loader.ConfigFileEnv != "-" {
-		if value, ok := os.LookupEnv(loader.ConfigFileEnv); ok {
-			filepaths = append(filepaths, strings.Split(value, ",")...)
+		if value, ok := loader.lookupEnv(loader.ConfigFileEnv); ok {
+			filepaths = append(filepaths, splitConfigFilePaths(value)...)
+		}
+	}
+
+	// Search config file when not given.
+	if len(filepaths) == 0 {
+		filepaths = loader.searchConfigFilePaths()
+	}
+
+	// Load optional config file at last.
+	for _, path := range loader.Optional {
+		if path, _ = optionalPath(path); path != "" {
+			filepaths = append(filepaths, optionalPrefix+path)
+		}
+	}
+
+	return filepaths
+}
+
+// findProfiles finds active profiles from flags, or environments when not
+// given by flags.
+func (loader *FileLoader) findProfiles(n *node.Node, args []string) Profiles {
+	profiles := Profiles{Key: loader.ProfilesKey}
+
+	var value string
+	if loader.ProfileFlag != "" {
+		if flattenMap, _, err := ParseFlagWithNode(args, n); err == nil {
+			value, _ = flattenMap.Value(loader.ProfileFlag)
+		}
+	}
+	if value == "" && loader.ProfileEnv != "" {
+		value, _ = loader.lookupEnv(loader.ProfileEnv)
+	}
+	for _, profile := range strings.Split(value, ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profiles.Active = append(profiles.Active, profile)
+		}
+	}
+
+	return profiles
+}
+
+func (loader *FileLoader) searchConfigFilePaths() []string {
+	var filepaths []string
+
+	for _, path := range loader.SearchPaths {
+		unset := false
+		path = os.Expand(path, func(key string) string {
+			value, _ := loader.lookupEnv(key)
+			unset = unset || value == ""
+			return value
+		})
+		if unset {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if !loader.SearchAll {
+			return []string{path}
 		}
+		filepaths = append([]string{path}, filepaths...)
 	}
 
 	return filepaths
 }
+
+func (loader *FileLoader) lookupEnv(key string) (string, bool) {
+	if loader.LookupEnv == nil {
+		return os.LookupEnv(key)
+	}
+	return loader.LookupEnv(key)
+}
+
+// splitConfigFilePaths splits paths by comma.
+func splitConfigFilePaths(value string) []string {
+	return strings.Split(value, ",")
+}
+
+// expandGlobs replaces glob patterns with matched supported files sorted
+// lexically, except overlay files of profiles.
+func (loader *FileLoader) expandGlobs(paths []string) ([]string, error) {
+	var filepaths []string
+
+	for _, path := range paths {
+		pattern, optional := optionalPath(path)
+		if !util.HasMeta(pattern) {
+			filepaths = append(filepaths, path)
+			continue
+		}
+		globMatches, err := util.Glob(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid config file pattern %s", path)
+		}
+		var matches []string
+		for _, match := range globMatches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() && isSupportedFile(match) {
+				matches = append(matches, match)
+			}
+		}
+		if len(matches) == 0 && loader.DisallowEmptyGlob && !optional {
+			return nil, errors.Errorf("no config file matches pattern %s", path)
+		}
+		filepaths = append(filepaths, withoutOverlays(matches)...)
+	}
+
+	return filepaths, nil
+}
+
+// expandConfigDirs replaces directories with their supported files in lexical
+// order, like conf.d/10-db.yaml and conf.d/20-cache.yaml, except overlay files
+// of profiles like conf.d/10-db.prod.yaml.
+func expandConfigDirs(paths []string) ([]string, error) {
+	var filepaths []string
+
+	for _, path := range paths {
+		dir, _ := optionalPath(path)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			filepaths = append(filepaths, path)
+			continue
+		}
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read config directory %s", dir)
+		}
+		var files []string
+		for _, info := range infos {
+			if !info.IsDir() && isSupportedFile(info.Name()) {
+				files = append(files, filepath.Join(dir, info.Name()))
+			}
+		}
+		filepaths = append(filepaths, withoutOverlays(files)...)
+	}
+
+	return filepaths, nil
+}
+
+// withoutOverlays removes overlay files like config.<profile>.yaml from paths
+// when config.yaml is also in paths, since they are only loaded with active
+// profiles.
+func withoutOverlays(paths []string) []string {
+	set := util.StringsToSet(paths)
+	var filepaths []string
+	for _, path := range paths {
+		ext := filepath.Ext(path)
+		base := strings.TrimSuffix(path, ext)
+		if profileExt := filepath.Ext(base); profileExt != "" && set[strings.TrimSuffix(base, profileExt)+ext] {
+			continue
+		}
+		filepaths = append(filepaths, path)
+	}
+	return filepaths
+}
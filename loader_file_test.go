@@ -0,0 +1,137 @@
+package configo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fileConfig struct {
+	Level   string `yaml:"level"`
+	Port    int    `yaml:"port"`
+	Unknown bool   `yaml:"unknown"`
+}
+
+func TestFileLoader(t *testing.T) {
+	env := MapEnv{"FIXTURES": "./fixtures", "FIXTURES_PROFILE": "dev"}
+	searchPaths := []string{
+		"./fixtures/missing.yaml",
+		"$UNSET/precedence.yaml",
+		"$FIXTURES/precedence.yaml",
+		"${FIXTURES}/unused.yaml",
+	}
+
+	testcases := []struct {
+		description string
+		loader      *FileLoader
+		args        []string
+		expected    *fileConfig
+		err         error
+	}{
+		{
+			description: "first found search path",
+			loader:      &FileLoader{SearchPaths: searchPaths},
+			expected:    &fileConfig{Level: "file", Port: 8080},
+		},
+		{
+			description: "all found search paths",
+			loader:      &FileLoader{SearchPaths: searchPaths, SearchAll: true},
+			expected:    &fileConfig{Level: "file", Port: 8080, Unknown: true},
+		},
+		{
+			description: "search paths ignored by flag",
+			loader:      &FileLoader{SearchPaths: searchPaths},
+			args:        []string{"-f", "./fixtures/unused.yaml"},
+			expected:    &fileConfig{Level: "file", Unknown: true},
+		},
+		{
+			description: "config directory",
+			loader:      &FileLoader{DisallowUnused: true},
+			args:        []string{"-f", "./fixtures/conf.d"},
+			expected:    &fileConfig{Level: "cache", Port: 5432},
+		},
+		{
+			description: "glob patterns",
+			loader:      &FileLoader{},
+			args:        []string{"-f", "./fixtures/conf.d/*.yml,./fixtures/conf.d/**/3*.yaml"},
+			expected:    &fileConfig{Level: "cache", Unknown: true},
+		},
+		{
+			description: "glob pattern matching unsupported files and directories",
+			loader:      &FileLoader{DisallowUnused: true},
+			args:        []string{"-f", "./fixtures/conf.d/*"},
+			expected:    &fileConfig{Level: "cache", Port: 5432},
+		},
+		{
+			description: "empty glob pattern",
+			loader:      &FileLoader{DisallowEmptyGlob: true},
+			args:        []string{"-f", "./fixtures/conf.d/*.json"},
+			err:         errors.New("no config file matches pattern ./fixtures/conf.d/*.json"),
+		},
+		{
+			description: "optional config files",
+			loader:      &FileLoader{Optional: []string{"./fixtures/missing.yaml", "./fixtures/conf.d/20-cache.yml"}},
+			args:        []string{"-f", "./fixtures/precedence.yaml,?./fixtures/missing.yaml,?./fixtures/missing/*.yaml"},
+			expected:    &fileConfig{Level: "cache", Port: 8080},
+		},
+		{
+			description: "missing config file",
+			loader:      &FileLoader{},
+			args:        []string{"-f", "./fixtures/missing.yaml"},
+			err:         errors.New("config file ./fixtures/missing.yaml does not exist"),
+		},
+		{
+			description: "profile from flag",
+			loader:      &FileLoader{DisallowUnused: true, ProfileFlag: "profile", ProfilesKey: "profiles"},
+			args:        []string{"-f", "./fixtures/profile/config.yaml", "--profile", "prod"},
+			expected:    &fileConfig{Level: "warn", Port: 443},
+		},
+		{
+			description: "profile from env",
+			loader:      &FileLoader{DisallowUnused: true, ProfileEnv: "FIXTURES_PROFILE", ProfilesKey: "profiles"},
+			args:        []string{"-f", "./fixtures/profile/config.yaml"},
+			expected:    &fileConfig{Level: "debug", Port: 80},
+		},
+		{
+			description: "no profile",
+			loader:      &FileLoader{DisallowUnused: true, ProfilesKey: "profiles"},
+			args:        []string{"-f", "./fixtures/profile/config.yaml"},
+			expected:    &fileConfig{Level: "info", Port: 80},
+		},
+		{
+			description: "profile sections without key",
+			loader:      &FileLoader{DisallowUnused: true, ProfileFlag: "profile"},
+			args:        []string{"-f", "./fixtures/profile/config.yaml", "--profile", "prod"},
+			err:         errors.New("unused keys profiles.prod.level, profiles.dev.level"),
+		},
+		{
+			description: "profile overlay in config directory",
+			loader:      &FileLoader{DisallowUnused: true, ProfileFlag: "profile"},
+			args:        []string{"-f", "./fixtures/conf.d", "--profile", "prod"},
+			expected:    &fileConfig{Level: "cache", Port: 6543},
+		},
+		{
+			description: "profile flag not opted in",
+			loader:      &FileLoader{DisallowUnused: true},
+			args:        []string{"-f", "./fixtures/conf.d", "--profile", "prod"},
+			expected:    &fileConfig{Level: "cache", Port: 5432},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			testcase.loader.LookupEnv = env.LookupEnv
+
+			var config fileConfig
+			err := (&Configo{Loader: testcase.loader}).Load(&config, testcase.args)
+			if testcase.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, testcase.expected, &config)
+			} else {
+				require.EqualError(t, err, testcase.err.Error())
+			}
+		})
+	}
+}
@@ -1,12 +1,28 @@
 package configo
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/han0110/configo/node"
+	"github.com/han0110/configo/util"
 	"github.com/pkg/errors"
 )
 
+const (
+	// tagFlag defines tag key for comma separated sources which flag's value
+	// could be read from.
+	tagFlag = "flag"
+	// flagSourceFile allows flag's value to be read from file by @path.
+	flagSourceFile = "file"
+	// flagSourceStdin allows flag's value to be read from stdin by -.
+	flagSourceStdin = "stdin"
+)
+
 var (
 	// defaultEscapeUnused defines default escaped unused keys.
 	defaultEscapeUnused = []string{defaultConfigFileFlag} // for FileLoader.
@@ -16,36 +32,160 @@ var (
 type FlagLoader struct {
 	DisallowUnused bool
 	EscapeUnused   []string
+	Stdin          io.Reader
 }
 
-var _ Loader = (*FlagLoader)(nil)
+var _ SourceLoader = (*FlagLoader)(nil)
 
 // Load implements ConfigLoader.
 func (loader *FlagLoader) Load(n *node.Node, args []string) error {
+	return loadSources(n, args, Loaders{loader}, nil, nil)
+}
+
+// Source implements SourceLoader.
+func (loader *FlagLoader) Source() Source {
+	return SourceFlag
+}
+
+// Parse implements SourceLoader, which also checks unused positional arguments
+// since they are known before filling.
+func (loader *FlagLoader) Parse(n *node.Node, args []string) (*util.FlattenMap, error) {
+	// Parse data
+	flattenMap, positionals, err := ParseFlagWithNode(args, n)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set positional arguments into nodes tagged with arg or args
+	unusedPositionals, err := setPositionals(n, flattenMap, positionals)
+	if err != nil {
+		return nil, err
+	}
+	if loader.DisallowUnused && len(unusedPositionals) > 0 {
+		return nil, errors.Errorf("unused argument %s", strings.Join(unusedPositionals, ", "))
+	}
+
+	// Read values from file or stdin for nodes opted in by tag
+	if err := loader.readValues(n, flattenMap); err != nil {
+		return nil, err
+	}
+
+	return flattenMap, nil
+}
+
+// CheckUnused implements SourceLoader.
+func (loader *FlagLoader) CheckUnused(n *node.Node, flattenMap *util.FlattenMap) error {
+	if !loader.DisallowUnused {
+		return nil
+	}
+
 	if len(loader.EscapeUnused) == 0 {
 		loader.EscapeUnused = defaultEscapeUnused
 	}
+	if unusedKeys := flattenMap.UnusedKeys(loader.EscapeUnused); len(unusedKeys) > 0 {
+		for i := range unusedKeys {
+			unusedKeys[i] = "--" + unusedKeys[i]
+		}
+		return errors.Errorf("unused flag %s", strings.Join(unusedKeys, ", "))
+	}
 
-	// Parse data
-	flattenMap, err := ParseFlag(args)
+	return nil
+}
+
+// setPositionals sets positional arguments into map by keys of nodes tagged
+// with arg or args, and returns unused ones only when any node is tagged.
+func setPositionals(n *node.Node, flattenMap *util.FlattenMap, positionals []string) ([]string, error) {
+	var rest *node.Node
+	used, tagged := 0, false
+
+	err := n.Walk(func(child *node.Node) error {
+		switch child.Arg {
+		case "":
+			return nil
+		case node.ArgRest:
+			rest = child
+		default:
+			index, err := strconv.Atoi(child.Arg)
+			if err != nil || index < 0 {
+				return errors.Errorf("invalid arg tag of %s", child.Key)
+			}
+			if index+1 > used {
+				used = index + 1
+			}
+			if index < len(positionals) {
+				flattenMap.Set(child.Key, positionals[index])
+			}
+		}
+		tagged = true
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if used > len(positionals) {
+		used = len(positionals)
+	}
+	if rest != nil {
+		for i, positional := range positionals[used:] {
+			flattenMap.Set(fmt.Sprintf("%s%s%d", rest.Key, util.CharDot, i), positional)
+		}
+		used = len(positionals)
 	}
 
-	// Fill data into node
-	if err := n.FillNode(flattenMap); err != nil {
-		return err
+	if !tagged {
+		return nil, nil
 	}
+	return positionals[used:], nil
+}
+
+// readValues replaces values of nodes tagged with flag:"file" by content of
+// file when value is @path (@@ escapes @), and of nodes tagged with
+// flag:"stdin" by content of stdin when value is -.
+func (loader *FlagLoader) readValues(n *node.Node, flattenMap *util.FlattenMap) error {
+	keys := append([]string{}, flattenMap.Keys()...)
+	values := flattenMap.Values()
+	stdinRead := false
 
-	// Check whether there are unused keys
-	if loader.DisallowUnused {
-		if unusedKeys := flattenMap.UnusedKeys(loader.EscapeUnused); len(unusedKeys) > 0 {
-			for i := range unusedKeys {
-				unusedKeys[i] = "--" + unusedKeys[i]
+	for i, key := range keys {
+		child := n.Find(key)
+		if child == nil {
+			continue
+		}
+		sources := util.StringsToSet(strings.Split(child.Tag.Get(tagFlag), ","))
+
+		value := values[i]
+		switch {
+		case sources[flagSourceFile] && strings.HasPrefix(value, "@@"):
+			value = value[1:]
+		case sources[flagSourceFile] && strings.HasPrefix(value, "@"):
+			data, err := ioutil.ReadFile(value[1:])
+			if err != nil {
+				return errors.Wrapf(err, "read value of --%s", util.ToDashCase(key))
+			}
+			value = string(data)
+		case sources[flagSourceStdin] && value == "-":
+			if stdinRead {
+				return errors.Errorf("read value of --%s: stdin has been read", util.ToDashCase(key))
 			}
-			return errors.Errorf("unused flag %s", strings.Join(unusedKeys, ", "))
+			data, err := ioutil.ReadAll(loader.stdin())
+			if err != nil {
+				return errors.Wrapf(err, "read value of --%s", util.ToDashCase(key))
+			}
+			value, stdinRead = string(data), true
+		default:
+			continue
 		}
+
+		flattenMap.Set(key, value)
 	}
 
 	return nil
 }
+
+func (loader *FlagLoader) stdin() io.Reader {
+	if loader.Stdin == nil {
+		return os.Stdin
+	}
+	return loader.Stdin
+}
@@ -0,0 +1,120 @@
+package configo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/han0110/configo/node"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type positionalConfig struct {
+	Level  string   `yaml:"level"`
+	Input  string   `yaml:"input" arg:"0"`
+	Output string   `yaml:"output" arg:"1"`
+	Rest   []string `yaml:"rest" args:"rest"`
+}
+
+type sourcedConfig struct {
+	Cert    string `yaml:"cert" flag:"file"`
+	Payload string `yaml:"payload" flag:"file,stdin"`
+	Name    string `yaml:"name"`
+}
+
+func TestFlagLoader(t *testing.T) { // nolint: funlen
+	testcases := []struct {
+		description string
+		element     interface{}
+		args        []string
+		stdin       string
+		expected    interface{}
+		err         error
+	}{
+		{
+			description: "positional arguments",
+			element:     &positionalConfig{},
+			args:        []string{"--level", "debug", "file1", "file2", "file3", "--", "--file4"},
+			expected: &positionalConfig{
+				Level:  "debug",
+				Input:  "file1",
+				Output: "file2",
+				Rest:   []string{"file3", "--file4"},
+			},
+		},
+		{
+			description: "missing positional arguments",
+			element:     &positionalConfig{},
+			args:        []string{"file1"},
+			expected:    &positionalConfig{Input: "file1"},
+		},
+		{
+			description: "unused positional arguments",
+			element: &struct {
+				Input string `arg:"0"`
+			}{},
+			args: []string{"file1", "file2"},
+			err:  errors.New("unused argument file2"),
+		},
+		{
+			description: "flags stop at positional arguments without tags",
+			element:     &struct{ Level string }{},
+			args:        []string{"--level", "info", "file1", "--level", "debug"},
+			expected:    &struct{ Level string }{Level: "info"},
+		},
+		{
+			description: "profile flag without file loader",
+			element:     &struct{ Level string }{},
+			args:        []string{"--profile", "prod"},
+			err:         errors.New("unused flag --profile"),
+		},
+		{
+			description: "values from file and stdin",
+			element:     &sourcedConfig{},
+			args:        []string{"--cert=@./fixtures/cert.pem", "--payload", "-", "--name", "@name"},
+			stdin:       `{"foo":"bar"}`,
+			expected: &sourcedConfig{
+				Cert:    "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+				Payload: `{"foo":"bar"}`,
+				Name:    "@name",
+			},
+		},
+		{
+			description: "escaped value from file",
+			element:     &sourcedConfig{},
+			args:        []string{"--cert", "@@cert", "--name=-"},
+			expected:    &sourcedConfig{Cert: "@cert", Name: "-"},
+		},
+		{
+			description: "value from missing file",
+			element:     &sourcedConfig{},
+			args:        []string{"--payload=@./fixtures/missing.json"},
+			err:         errors.New("read value of --payload: open ./fixtures/missing.json: no such file or directory"),
+		},
+		{
+			description: "nested value from missing file",
+			element: &struct {
+				TLS sourcedConfig `yaml:"tls"`
+			}{},
+			args: []string{"--tls-cert", "@./fixtures/missing.pem"},
+			err:  errors.New("read value of --tls-cert: open ./fixtures/missing.pem: no such file or directory"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			n, err := node.New(testcase.element, node.EncoderOption{})
+			require.NoError(t, err)
+
+			loader := &FlagLoader{DisallowUnused: true, Stdin: strings.NewReader(testcase.stdin)}
+			err = loader.Load(n, testcase.args)
+			if testcase.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, testcase.expected, testcase.element)
+			} else {
+				require.EqualError(t, err, testcase.err.Error())
+			}
+		})
+	}
+}
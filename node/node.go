@@ -3,8 +3,11 @@ package node
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/han0110/configo/util"
 )
 
 const (
@@ -12,6 +15,27 @@ const (
 	defaultTagName = "yaml"
 	// defaultTagDescription defines default tag key for description.
 	defaultTagDescription = "description"
+	// tagOneOf defines tag key for comma separated allowed values.
+	tagOneOf = "oneof"
+	// tagMin defines tag key for minimum value.
+	tagMin = "min"
+	// tagMax defines tag key for maximum value.
+	tagMax = "max"
+	// tagMinLen defines tag key for minimum length of string, slice or map.
+	tagMinLen = "minlen"
+	// tagMaxLen defines tag key for maximum length of string, slice or map.
+	tagMaxLen = "maxlen"
+	// tagPattern defines tag key for regular expression which string matches.
+	tagPattern = "pattern"
+	// tagArg defines tag key for index of positional argument.
+	tagArg = "arg"
+	// tagArgs defines tag key for the rest positional arguments.
+	tagArgs = "args"
+)
+
+const (
+	// ArgRest is Node.Arg for the rest positional arguments.
+	ArgRest = "rest"
 )
 
 // New encodes element into node.
@@ -40,8 +64,27 @@ type Node struct {
 	Name        string
 	Description string
 	FiledName   string
+	Tag         reflect.StructTag
 	Value       reflect.Value
 	Children    []*Node
+	Enum        []string
+	Min         string
+	Max         string
+	MinLen      *int
+	MaxLen      *int
+	Pattern     string
+	Arg         string
+	// Segments are names of node and its ancestors from the root, where item
+	// in map or slice is an empty segment. Nodes with different segments
+	// could share the same key, e.g. max_conns and max.conns.
+	Segments []string
+
+	// decode is set when node is a leaf decoded from a single string, and
+	// encode is optionally set to serialize it back.
+	decode DecodeHook
+	encode EncodeHook
+	// pattern is compiled from Pattern.
+	pattern *regexp.Regexp
 }
 
 // WalkCallback defines function called when walk.
@@ -82,42 +125,106 @@ func (node *Node) Flat() []*Node {
 
 // SerializeValue serializes node's value.
 func (node *Node) SerializeValue() string {
-	switch node.Value.Kind() {
-	case reflect.Slice:
+	return node.serialize(node.Value)
+}
+
+func (node *Node) serialize(rValue reflect.Value) string {
+	if rValue.Kind() == reflect.Ptr && !rValue.IsNil() {
+		rValue = rValue.Elem()
+	}
+
+	switch {
+	case node.encode != nil:
+		return node.encode(rValue)
+	case node.isDynamic() && rValue.Kind() == reflect.Slice:
 		var str []string
-		for i, l := 0, node.Value.Len(); i < l; i++ {
-			str = append(str, fmt.Sprint(node.Value.Index(i)))
+		for i, l := 0, rValue.Len(); i < l; i++ {
+			str = append(str, node.Children[0].serialize(rValue.Index(i)))
 		}
 		return fmt.Sprintf("[%s]", strings.Join(str, ","))
-	case reflect.Map:
+	case node.isDynamic() && rValue.Kind() == reflect.Map:
 		var str []string
-		for iter := node.Value.MapRange(); iter.Next(); {
-			str = append(str, fmt.Sprintf("%s=%s", iter.Key().String(), fmt.Sprint(iter.Value())))
+		for iter := rValue.MapRange(); iter.Next(); {
+			str = append(str, fmt.Sprintf("%s=%s", iter.Key().String(), node.Children[0].serialize(iter.Value())))
 		}
+		sort.Strings(str)
 		return fmt.Sprintf("{%s}", strings.Join(str, ","))
 	default:
-		return fmt.Sprint(node.Value.Interface())
+		return fmt.Sprint(rValue.Interface())
+	}
+}
+
+// FlattenValues calls set with key and serialized value of each non-zero leaf,
+// including items of map or slice.
+func (node *Node) FlattenValues(set func(key, value string)) {
+	node.flattenValues(node.Value, node.Key, set)
+}
+
+func (node *Node) flattenValues(rValue reflect.Value, key string, set func(key, value string)) {
+	if rValue.Kind() == reflect.Ptr {
+		if rValue.IsNil() {
+			return
+		}
+		rValue = rValue.Elem()
+	}
+
+	switch {
+	case node.isDynamic() && rValue.Kind() == reflect.Slice:
+		for i, l := 0, rValue.Len(); i < l; i++ {
+			node.Children[0].flattenValues(rValue.Index(i), fmt.Sprintf("%s%s%d", key, util.CharDot, i), set)
+		}
+	case node.isDynamic() && rValue.Kind() == reflect.Map:
+		keys := rValue.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, mapKey := range keys {
+			node.Children[0].flattenValues(rValue.MapIndex(mapKey), key+util.CharDot+mapKey.String(), set)
+		}
+	case node.decode != nil || len(node.Children) == 0:
+		if !rValue.IsZero() {
+			set(key, node.serialize(rValue))
+		}
+	default:
+		for _, child := range node.Children {
+			child.flattenValues(rValue.FieldByName(child.FiledName), key+child.Key[len(node.Key):], set)
+		}
 	}
 }
 
 func (node *Node) clone() *Node {
+	return node.cloneTo(reflect.New(node.Value.Type()).Elem())
+}
+
+// cloneTo clones node with value bound to rValue, and allocates pointer as
+// encoder does.
+func (node *Node) cloneTo(rValue reflect.Value) *Node {
+	if rValue.Kind() == reflect.Ptr && rValue.IsNil() {
+		rValue.Set(reflect.New(rValue.Type().Elem()))
+	}
 	clone := &Node{
 		Key:         node.Key,
 		Name:        node.Name,
 		Description: node.Description,
 		FiledName:   node.FiledName,
-		Value:       reflect.New(node.Value.Type()).Elem(),
-	}
-	if node.Value.Kind() == reflect.Ptr {
-		clone.Value = reflect.New(node.Value.Type().Elem())
+		Tag:         node.Tag,
+		Value:       rValue,
+		Enum:        node.Enum,
+		Min:         node.Min,
+		Max:         node.Max,
+		MinLen:      node.MinLen,
+		MaxLen:      node.MaxLen,
+		Pattern:     node.Pattern,
+		Arg:         node.Arg,
+		Segments:    node.Segments,
+		decode:      node.decode,
+		encode:      node.encode,
+		pattern:     node.pattern,
 	}
 	if node.isDynamic() {
 		clone.Children = []*Node{node.Children[0].clone()}
 	} else {
 		for _, child := range node.Children {
-			childClone := child.clone()
-			childClone.Value = reflect.Indirect(clone.Value).FieldByName(childClone.FiledName)
-			clone.Children = append(clone.Children, childClone)
+			childValue := reflect.Indirect(rValue).FieldByName(child.FiledName)
+			clone.Children = append(clone.Children, child.cloneTo(childValue))
 		}
 	}
 	return clone
@@ -131,6 +238,101 @@ func (node *Node) reKey(oldkey, newkey string) {
 }
 
 func (node *Node) isDynamic() bool {
+	if node.decode != nil {
+		return false
+	}
 	kind := reflect.Indirect(node.Value).Kind()
 	return kind == reflect.Map || kind == reflect.Slice
 }
+
+// Find finds node by key, where key of item in map or slice is matched by
+// the template child.
+func (node *Node) Find(key string) *Node {
+	if node.Key == key {
+		return node
+	}
+
+	if node.isDynamic() {
+		prefix := node.Key + util.CharDot
+		if node.Key == "" || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		templateKey := prefix
+		if rest := strings.SplitN(key[len(prefix):], util.CharDot, 2); len(rest) == 2 {
+			templateKey += util.CharDot + rest[1]
+		}
+		return node.Children[0].Find(templateKey)
+	}
+
+	for _, child := range node.Children {
+		if child.Key == key || strings.HasPrefix(key, child.Key+util.CharDot) {
+			if found := child.Find(key); found != nil {
+				return found
+			}
+		}
+	}
+
+	return nil
+}
+
+// FindAll finds all nodes by key like Find, since nodes with different
+// segments could share the same key.
+func (node *Node) FindAll(key string) (found []*Node) {
+	if node.Key == key {
+		return []*Node{node}
+	}
+
+	if node.isDynamic() {
+		prefix := node.Key + util.CharDot
+		if node.Key == "" || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		templateKey := prefix
+		if rest := strings.SplitN(key[len(prefix):], util.CharDot, 2); len(rest) == 2 {
+			templateKey += util.CharDot + rest[1]
+		}
+		return node.Children[0].FindAll(templateKey)
+	}
+
+	for _, child := range node.Children {
+		if child.Key == key || strings.HasPrefix(key, child.Key+util.CharDot) {
+			found = append(found, child.FindAll(key)...)
+		}
+	}
+
+	return found
+}
+
+// segmentsOf returns segments of key matched by node, where empty segments
+// of item in map or slice are replaced by the corresponding part of key.
+func (node *Node) segmentsOf(key string) []string {
+	parts := strings.Split(key, util.CharDot)
+	segments := make([]string, 0, len(node.Segments))
+	for _, segment := range node.Segments {
+		count := 1
+		if segment != "" {
+			count = len(strings.Split(util.ToDotCase(segment), util.CharDot))
+		}
+		if count > len(parts) {
+			count = len(parts)
+		}
+		segments = append(segments, strings.Join(parts[:count], util.CharDot))
+		parts = parts[count:]
+	}
+	return segments
+}
+
+// IsDynamic checks whether node is a map or slice, whose items are filled by
+// keys under node's key.
+func (node *Node) IsDynamic() bool {
+	return node.isDynamic()
+}
+
+// IsBool checks whether node's value, or item's value when node is a map or
+// slice, is boolean.
+func (node *Node) IsBool() bool {
+	for node.isDynamic() {
+		node = node.Children[0]
+	}
+	return node.decode == nil && reflect.Indirect(node.Value).Kind() == reflect.Bool
+}
@@ -0,0 +1,272 @@
+package node
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decoder is implemented by types which decode themselves from a single
+// string, and such types are treated as leaf when encoding.
+type Decoder interface {
+	Decode(value string) error
+}
+
+// DecodeHook decodes value into rValue of registered type, and the type is
+// treated as leaf when encoding.
+type DecodeHook func(rValue reflect.Value, value string) error
+
+// EncodeHook encodes rValue of registered type into a single string, which
+// is the reverse of DecodeHook.
+type EncodeHook func(rValue reflect.Value) string
+
+// DefaultDecodeHooks defines built-in decode hooks for common types in stdlib,
+// which are consulted after hooks in EncoderOption.
+var DefaultDecodeHooks = map[reflect.Type]DecodeHook{
+	reflect.TypeOf(time.Duration(0)): func(rValue reflect.Value, value string) error {
+		val, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		rValue.SetInt(int64(val))
+		return nil
+	},
+	reflect.TypeOf(url.URL{}): func(rValue reflect.Value, value string) error {
+		val, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		rValue.Set(reflect.ValueOf(*val))
+		return nil
+	},
+	reflect.TypeOf(net.IP{}): func(rValue reflect.Value, value string) error {
+		val := net.ParseIP(value)
+		if val == nil {
+			return fmt.Errorf("invalid IP address: %s", value)
+		}
+		rValue.SetBytes(val)
+		return nil
+	},
+	reflect.TypeOf(net.IPNet{}): func(rValue reflect.Value, value string) error {
+		_, val, err := net.ParseCIDR(value)
+		if err != nil {
+			return err
+		}
+		rValue.Set(reflect.ValueOf(*val))
+		return nil
+	},
+	reflect.TypeOf(regexp.Regexp{}): func(rValue reflect.Value, value string) error {
+		val, err := regexp.Compile(value)
+		if err != nil {
+			return err
+		}
+		rValue.Set(reflect.ValueOf(val).Elem())
+		return nil
+	},
+	reflect.TypeOf(os.FileMode(0)): func(rValue reflect.Value, value string) error {
+		val, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return err
+		}
+		rValue.SetUint(val)
+		return nil
+	},
+	reflect.TypeOf(ByteSize(0)): func(rValue reflect.Value, value string) error {
+		val, err := ParseByteSize(value)
+		if err != nil {
+			return err
+		}
+		rValue.SetUint(uint64(val))
+		return nil
+	},
+	reflect.TypeOf(big.Int{}): func(rValue reflect.Value, value string) error {
+		if _, ok := rValue.Addr().Interface().(*big.Int).SetString(value, 0); !ok {
+			return fmt.Errorf("invalid big integer: %s", value)
+		}
+		return nil
+	},
+}
+
+// DefaultEncodeHooks defines built-in encode hooks matching DefaultDecodeHooks.
+var DefaultEncodeHooks = map[reflect.Type]EncodeHook{
+	reflect.TypeOf(time.Duration(0)): encodeByStringer,
+	reflect.TypeOf(url.URL{}):        encodeByStringer,
+	reflect.TypeOf(net.IP{}):         encodeByStringer,
+	reflect.TypeOf(net.IPNet{}):      encodeByStringer,
+	reflect.TypeOf(regexp.Regexp{}):  encodeByStringer,
+	reflect.TypeOf(os.FileMode(0)): func(rValue reflect.Value) string {
+		return fmt.Sprintf("%#o", rValue.Uint())
+	},
+	reflect.TypeOf(ByteSize(0)): encodeByStringer,
+	reflect.TypeOf(big.Int{}):   encodeByStringer,
+}
+
+var (
+	decoderType         = reflect.TypeOf((*Decoder)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// decodeHookOf returns hook to decode type as leaf, or nil if type should be
+// encoded by its kind.
+func decodeHookOf(rType reflect.Type, hooks map[reflect.Type]DecodeHook) DecodeHook {
+	if hook, ok := hooks[rType]; ok {
+		return hook
+	}
+	if hook, ok := DefaultDecodeHooks[rType]; ok {
+		return hook
+	}
+
+	// Pointer receiver's method set includes value receiver's one.
+	rPtrType := reflect.PtrTo(rType)
+	switch {
+	case rPtrType.Implements(decoderType):
+		return decodeByDecoder
+	case rPtrType.Implements(textUnmarshalerType):
+		return decodeByTextUnmarshaler
+	}
+
+	return nil
+}
+
+func decodeByDecoder(rValue reflect.Value, value string) error {
+	return addressable(rValue, func(rPtr reflect.Value) error {
+		return rPtr.Interface().(Decoder).Decode(value)
+	})
+}
+
+func decodeByTextUnmarshaler(rValue reflect.Value, value string) error {
+	return addressable(rValue, func(rPtr reflect.Value) error {
+		return rPtr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	})
+}
+
+// addressable calls decode with pointer to rValue, and decodes into a copy
+// then sets it back when rValue is not addressable.
+func addressable(rValue reflect.Value, decode func(rPtr reflect.Value) error) error {
+	rPtr := pointerTo(rValue)
+	if err := decode(rPtr); err != nil {
+		return err
+	}
+	if !rValue.CanAddr() {
+		rValue.Set(rPtr.Elem())
+	}
+	return nil
+}
+
+// encodeHookOf returns hook to encode type of leaf, or nil if type should be
+// formatted by fmt.
+func encodeHookOf(rType reflect.Type, hooks map[reflect.Type]EncodeHook) EncodeHook {
+	if hook, ok := hooks[rType]; ok {
+		return hook
+	}
+	if hook, ok := DefaultEncodeHooks[rType]; ok {
+		return hook
+	}
+	if reflect.PtrTo(rType).Implements(textMarshalerType) {
+		return encodeByTextMarshaler
+	}
+	return nil
+}
+
+func encodeByStringer(rValue reflect.Value) string {
+	return pointerTo(rValue).Interface().(fmt.Stringer).String()
+}
+
+func encodeByTextMarshaler(rValue reflect.Value) string {
+	text, err := pointerTo(rValue).Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// pointerTo returns pointer to rValue, or pointer to a copy of rValue when it
+// is not addressable.
+func pointerTo(rValue reflect.Value) reflect.Value {
+	if rValue.CanAddr() {
+		return rValue.Addr()
+	}
+	rPtr := reflect.New(rValue.Type())
+	rPtr.Elem().Set(rValue)
+	return rPtr
+}
+
+// ByteSize represents a size in bytes, which is decoded from string with
+// optional case-insensitive unit (e.g. 512MiB, 1.5GB). Units KB, MB, GB, TB
+// and PB are decimal, while KiB, MiB, GiB, TiB and PiB are binary, and single
+// letter K, M, G, T and P are binary as well (e.g. 512M is 512MiB).
+type ByteSize uint64
+
+var byteSizeUnits = []struct {
+	name string
+	size uint64
+}{
+	{"PiB", 1 << 50},
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"PB", 1e15},
+	{"TB", 1e12},
+	{"GB", 1e9},
+	{"MB", 1e6},
+	{"KB", 1e3},
+	{"B", 1},
+}
+
+// ParseByteSize parses string with optional unit into ByteSize.
+func ParseByteSize(str string) (ByteSize, error) {
+	str = strings.TrimSpace(str)
+	index := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if index < 0 {
+		index = len(str)
+	}
+
+	number, err := strconv.ParseFloat(str[:index], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size: %s", str)
+	}
+
+	unit := strings.TrimSpace(str[index:])
+	if unit == "" {
+		return byteSizeOf(str, number)
+	}
+	for _, byteSizeUnit := range byteSizeUnits {
+		if strings.EqualFold(unit, byteSizeUnit.name) || strings.EqualFold(unit, byteSizeUnit.name[:1]) {
+			return byteSizeOf(str, number*float64(byteSizeUnit.size))
+		}
+	}
+
+	return 0, fmt.Errorf("invalid byte size unit: %s", unit)
+}
+
+// byteSizeOf converts size in bytes into ByteSize, which must be less than
+// 2^64.
+func byteSizeOf(str string, size float64) (ByteSize, error) {
+	if size >= math.Exp2(64) {
+		return 0, fmt.Errorf("byte size %s overflows", str)
+	}
+	return ByteSize(size), nil
+}
+
+// String formats size with the largest binary unit which divides it.
+func (size ByteSize) String() string {
+	for _, byteSizeUnit := range byteSizeUnits[:5] {
+		if size != 0 && uint64(size)%byteSizeUnit.size == 0 {
+			return fmt.Sprintf("%d%s", uint64(size)/byteSizeUnit.size, byteSizeUnit.name)
+		}
+	}
+	return fmt.Sprintf("%dB", uint64(size))
+}
@@ -0,0 +1,180 @@
+package node
+
+import (
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// check checks whether value of key satisfies constraints of node, where value
+// is the string reported in errors.
+func (node *Node) check(rValue reflect.Value, key, value string) error {
+	if err := node.checkOneOf(rValue, key, value); err != nil {
+		return err
+	}
+	if err := node.checkRange(rValue, key, value); err != nil {
+		return err
+	}
+	if rValue.Kind() == reflect.String {
+		if err := node.checkLen(rValue, key); err != nil {
+			return err
+		}
+		if node.pattern != nil && !node.pattern.MatchString(rValue.String()) {
+			return errors.Errorf(
+				"invalid value %q of %s, expected to match pattern %s",
+				value,
+				key,
+				node.Pattern,
+			)
+		}
+	}
+	return nil
+}
+
+// checkFinal checks constraints of node on its final value of key, which is
+// also checked when no key is filled.
+func (node *Node) checkFinal(rValue reflect.Value, key string) error {
+	switch {
+	case node.isDynamic():
+		return node.checkLen(rValue, key)
+	case node.decode == nil && len(node.Children) > 0:
+		return nil
+	default:
+		return node.check(rValue, key, node.serialize(rValue))
+	}
+}
+
+// checkOneOf checks whether decoded value equals to one of decoded allowed values.
+func (node *Node) checkOneOf(rValue reflect.Value, key, value string) error {
+	if len(node.Enum) == 0 {
+		return nil
+	}
+	for _, allowed := range node.Enum {
+		rAllowed := reflect.New(rValue.Type()).Elem()
+		if err := (&nodeFiller{}).decode(node, rAllowed, allowed); err != nil {
+			continue
+		}
+		if reflect.DeepEqual(rAllowed.Interface(), rValue.Interface()) {
+			return nil
+		}
+	}
+	return errors.Errorf(
+		"invalid value %q of %s, expected one of %s",
+		value,
+		key,
+		strings.Join(node.Enum, ", "),
+	)
+}
+
+// checkRange checks whether decoded value is between decoded min and max.
+func (node *Node) checkRange(rValue reflect.Value, key, value string) error {
+	bounds := []struct {
+		tag   string
+		bound string
+		sign  int
+		text  string
+	}{
+		{tagMin, node.Min, -1, "at least"},
+		{tagMax, node.Max, 1, "at most"},
+	}
+	for _, bound := range bounds {
+		if bound.bound == "" {
+			continue
+		}
+		rBound := reflect.New(rValue.Type()).Elem()
+		if err := (&nodeFiller{}).decode(node, rBound, bound.bound); err != nil {
+			return errors.Wrapf(err, "invalid %s tag of %s", bound.tag, key)
+		}
+		sign, err := compare(rValue, rBound)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s tag of %s", bound.tag, key)
+		}
+		if sign == bound.sign {
+			return errors.Errorf(
+				"invalid value %q of %s, expected %s %s",
+				value,
+				key,
+				bound.text,
+				bound.bound,
+			)
+		}
+	}
+	return nil
+}
+
+// checkLen checks whether length of string, slice or map of key is between
+// minlen and maxlen.
+func (node *Node) checkLen(rValue reflect.Value, key string) error {
+	length := 0
+	switch rValue.Kind() {
+	case reflect.String:
+		length = utf8.RuneCountInString(rValue.String())
+	case reflect.Slice, reflect.Map:
+		length = rValue.Len()
+	default:
+		return nil
+	}
+	if node.MinLen != nil && length < *node.MinLen {
+		return errors.Errorf("invalid length %d of %s, expected at least %d", length, key, *node.MinLen)
+	}
+	if node.MaxLen != nil && length > *node.MaxLen {
+		return errors.Errorf("invalid length %d of %s, expected at most %d", length, key, *node.MaxLen)
+	}
+	return nil
+}
+
+// checkTags checks whether constraint tags of node apply to its kind, which
+// are reported by key of field, and decodes bounds of min and max ahead.
+func (node *Node) checkTags(key string) error {
+	kind := reflect.Indirect(node.Value).Kind()
+	if (node.MinLen != nil || node.MaxLen != nil) && kind != reflect.String && !node.isDynamic() {
+		return errors.Errorf("invalid length tag of %s, expected string, slice or map but got %s", key, kind)
+	}
+	if node.isDynamic() {
+		return nil
+	}
+	if node.Pattern != "" && kind != reflect.String {
+		return errors.Errorf("invalid %s tag of %s, expected string but got %s", tagPattern, key, kind)
+	}
+	for _, bound := range []struct{ tag, bound string }{{tagMin, node.Min}, {tagMax, node.Max}} {
+		tag, bound := bound.tag, bound.bound
+		if bound == "" {
+			continue
+		}
+		rBound := reflect.New(reflect.Indirect(node.Value).Type()).Elem()
+		if err := (&nodeFiller{}).decode(node, rBound, bound); err != nil {
+			return errors.Wrapf(err, "invalid %s tag of %s", tag, key)
+		}
+		if _, err := compare(rBound, rBound); err != nil {
+			return errors.Wrapf(err, "invalid %s tag of %s", tag, key)
+		}
+	}
+	return nil
+}
+
+// compare returns -1, 0 or 1 when a is less than, equal to or greater than b.
+func compare(a, b reflect.Value) (int, error) {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int() < b.Int(), a.Int() > b.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(a.Uint() < b.Uint(), a.Uint() > b.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float() < b.Float(), a.Float() > b.Float()), nil
+	default:
+		return 0, errors.Errorf("unable to compare value of kind %s", a.Kind())
+	}
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -3,6 +3,9 @@ package node
 import (
 	"fmt"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/han0110/configo/util"
 	"github.com/pkg/errors"
@@ -12,6 +15,8 @@ import (
 type EncoderOption struct {
 	TagName        string
 	TagDescription string
+	DecodeHooks    map[reflect.Type]DecodeHook
+	EncodeHooks    map[reflect.Type]EncodeHook
 }
 
 type encoder struct {
@@ -19,6 +24,14 @@ type encoder struct {
 }
 
 func (coder *encoder) setNode(node *Node, rValue reflect.Value) error {
+	if rValue.Kind() != reflect.Ptr {
+		if hook := decodeHookOf(rValue.Type(), coder.DecodeHooks); hook != nil {
+			node.decode = hook
+			node.encode = encodeHookOf(rValue.Type(), coder.EncodeHooks)
+			return nil
+		}
+	}
+
 	switch rValue.Kind() {
 	case reflect.Ptr:
 		if rValue.IsNil() {
@@ -64,7 +77,7 @@ func (coder *encoder) setStruct(node *Node, rValue reflect.Value) error {
 			continue
 		}
 
-		if ok := IsSupportedType(childField.Type); !ok {
+		if ok := coder.isLeafType(childField.Type) || IsSupportedType(childField.Type); !ok {
 			return errors.Errorf("type %s is not supported", childField.Type)
 		}
 
@@ -91,12 +104,25 @@ func (coder *encoder) setStruct(node *Node, rValue reflect.Value) error {
 			Name:        childName,
 			Description: childField.Tag.Get(coder.TagDescription),
 			FiledName:   childField.Name,
+			Tag:         childField.Tag,
 			Value:       childValue,
+			Segments:    append(append([]string{}, node.Segments...), childName),
+		}
+		if err := coder.setConstraints(child, &childField); err != nil {
+			return err
+		}
+
+		child.Arg = childField.Tag.Get(tagArg)
+		if childField.Tag.Get(tagArgs) == ArgRest {
+			child.Arg = ArgRest
 		}
 
 		if err := coder.setNode(child, child.Value); err != nil {
 			return err
 		}
+		if err := child.checkTags(child.Key); err != nil {
+			return err
+		}
 
 		node.Children = append(node.Children, child)
 	}
@@ -104,6 +130,47 @@ func (coder *encoder) setStruct(node *Node, rValue reflect.Value) error {
 	return nil
 }
 
+func (coder *encoder) setConstraints(node *Node, rField *reflect.StructField) error {
+	if oneOf := rField.Tag.Get(tagOneOf); oneOf != "" {
+		for _, value := range strings.Split(oneOf, ",") {
+			node.Enum = append(node.Enum, strings.TrimSpace(value))
+		}
+	}
+
+	node.Min = rField.Tag.Get(tagMin)
+	node.Max = rField.Tag.Get(tagMax)
+
+	var err error
+	if node.MinLen, err = parseLenTag(rField, tagMinLen); err != nil {
+		return errors.Wrapf(err, "invalid %s tag of %s", tagMinLen, node.Key)
+	}
+	if node.MaxLen, err = parseLenTag(rField, tagMaxLen); err != nil {
+		return errors.Wrapf(err, "invalid %s tag of %s", tagMaxLen, node.Key)
+	}
+
+	if node.Pattern = rField.Tag.Get(tagPattern); node.Pattern != "" {
+		pattern, err := regexp.Compile(node.Pattern)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s tag of %s", tagPattern, node.Key)
+		}
+		node.pattern = pattern
+	}
+
+	return nil
+}
+
+func parseLenTag(rField *reflect.StructField, tag string) (*int, error) {
+	value := rField.Tag.Get(tag)
+	if value == "" {
+		return nil, nil
+	}
+	length, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &length, nil
+}
+
 func (coder *encoder) setDynamic(node *Node, rValue reflect.Value) error {
 	var childKey, childDescription string
 
@@ -120,14 +187,26 @@ func (coder *encoder) setDynamic(node *Node, rValue reflect.Value) error {
 		panic(errors.Errorf("unexpected call on setDynamic by node of kind %s", rValue.Kind()))
 	}
 
+	// Constraints of value apply to each item in map or slice, but
+	// constraints of length apply to map or slice itself.
 	child := &Node{
 		Key:         childKey,
 		Description: childDescription,
+		Tag:         node.Tag,
 		Value:       reflect.New(rValue.Type().Elem()).Elem(),
+		Enum:        node.Enum,
+		Min:         node.Min,
+		Max:         node.Max,
+		Pattern:     node.Pattern,
+		Segments:    append(append([]string{}, node.Segments...), ""),
+		pattern:     node.pattern,
 	}
 	if err := coder.setNode(child, child.Value); err != nil {
 		return err
 	}
+	if err := child.checkTags(node.Key); err != nil {
+		return err
+	}
 
 	// Add a template child for clone when filling data
 	node.Children = []*Node{child}
@@ -135,6 +214,14 @@ func (coder *encoder) setDynamic(node *Node, rValue reflect.Value) error {
 	return nil
 }
 
+// isLeafType checks whether type is decoded from a single string.
+func (coder *encoder) isLeafType(rType reflect.Type) bool {
+	for rType.Kind() == reflect.Ptr {
+		rType = rType.Elem()
+	}
+	return decodeHookOf(rType, coder.DecodeHooks) != nil
+}
+
 // IsExported checks whether field is exported..
 func IsExported(rField *reflect.StructField) bool {
 	return rField.PkgPath == ""
@@ -1,7 +1,6 @@
 package node
 
 import (
-	"encoding"
 	"reflect"
 	"strconv"
 
@@ -15,44 +14,189 @@ type FlattenMap interface {
 	ChildrenByPrefix(prefix string) []string
 }
 
+// SegmentedFlattenMap is FlattenMap which tells apart values by segments of
+// key, for nodes sharing the same key but having different segments.
+type SegmentedFlattenMap interface {
+	FlattenMap
+	SegmentedValue(key string, segments []string) (value string, ok bool)
+}
+
+// NullableFlattenMap is FlattenMap which sets key to explicit null, to reset
+// pointer to nil, clear slice or map, or zero value of key, or to explicit
+// empty list or mapping, to clear slice or map only.
+type NullableFlattenMap interface {
+	FlattenMap
+	IsNull(key string) bool
+	IsEmpty(key string) bool
+}
+
+// FillError describes an error on filling value of key.
+type FillError struct {
+	Key string
+	Err error
+}
+
+// Error implements error.
+func (err *FillError) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *FillError) Unwrap() error {
+	return err.Err
+}
+
 type nodeFiller struct {
 	FlattenMap
 }
 
 func (filler *nodeFiller) fill(node *Node) error {
-	callback := func(n *Node) error {
-		return filler.fillNode(n, n.Value)
+	return filler.fillNullable(node, false)
+}
+
+// fillNullable fills node, which is reset first when node or its parent is
+// set to explicit null, or cleared first when node is map or slice set to
+// explicit empty, while struct set to explicit empty is kept.
+func (filler *nodeFiller) fillNullable(node *Node, null bool) error {
+	null = null || filler.isNull(node.Key)
+	if null && !filler.reset(node) {
+		return nil
+	}
+	if !null && filler.isEmpty(node.Key) {
+		switch {
+		case node.isDynamic():
+			filler.clear(node.Value)
+		case len(node.Children) == 0:
+			return nil
+		}
 	}
 
-	if err := node.Walk(callback); err != nil {
-		return err
+	if len(node.Children) == 0 || node.isDynamic() {
+		return filler.fillNode(node, node.Value)
+	}
+
+	for _, child := range node.Children {
+		if err := filler.fillNullable(child, null); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// isNull checks whether key is set to explicit null when supported.
+func (filler *nodeFiller) isNull(key string) bool {
+	if flattenMap, ok := filler.FlattenMap.(NullableFlattenMap); ok && key != "" {
+		return flattenMap.IsNull(key)
+	}
+	return false
+}
+
+// isEmpty checks whether key is set to explicit empty when supported.
+func (filler *nodeFiller) isEmpty(key string) bool {
+	if flattenMap, ok := filler.FlattenMap.(NullableFlattenMap); ok && key != "" {
+		return flattenMap.IsEmpty(key)
+	}
+	return false
+}
+
+// clear sets map or slice, allocated when it's behind nil pointer, to empty.
+func (filler *nodeFiller) clear(rValue reflect.Value) {
+	for rValue.Kind() == reflect.Ptr {
+		if rValue.IsNil() {
+			rValue.Set(reflect.New(rValue.Type().Elem()))
+		}
+		rValue = rValue.Elem()
+	}
+	if rValue.Kind() == reflect.Map {
+		rValue.Set(reflect.MakeMapWithSize(rValue.Type(), 0))
+	} else {
+		rValue.Set(reflect.MakeSlice(rValue.Type(), 0, 0))
+	}
+}
+
+// reset resets node's value to zero, and returns whether node should be
+// filled further by its value or keys under it, which are set after null by
+// higher priority source. Struct with keys under it is reset by its children.
+func (filler *nodeFiller) reset(node *Node) bool {
+	rValue := node.Value
+	if len(node.Children) == 0 {
+		rValue.Set(reflect.Zero(rValue.Type()))
+		_, ok := filler.value(node)
+		return ok && !filler.isNull(node.Key)
+	}
+	if len(filler.FlattenMap.ChildrenByPrefix(node.Key+util.CharDot)) == 0 {
+		rValue.Set(reflect.Zero(rValue.Type()))
+		return node.isDynamic() && rValue.Kind() != reflect.Ptr
+	}
+	if node.isDynamic() {
+		rValue = reflect.Indirect(rValue)
+		rValue.Set(reflect.Zero(rValue.Type()))
+	}
+	return true
+}
+
 func (filler *nodeFiller) fillNode(node *Node, rValue reflect.Value) error {
-	switch rValue.Kind() {
-	case reflect.Map:
+	switch {
+	case rValue.Kind() == reflect.Ptr:
+		if rValue.IsNil() {
+			rValue.Set(reflect.New(rValue.Type().Elem()))
+		}
+		return filler.fillNode(node, rValue.Elem())
+	case node.decode != nil:
+		if value, ok := filler.value(node); ok {
+			return filler.fillLeaf(node, rValue, value)
+		}
+	case rValue.Kind() == reflect.Map:
 		return filler.fillMap(node, rValue)
-	case reflect.Slice:
+	case rValue.Kind() == reflect.Slice:
 		return filler.fillSlice(node, rValue)
-	case reflect.Ptr:
-		return filler.fillNode(node, rValue.Elem())
 	default:
-		if value, ok := filler.FlattenMap.Value(node.Key); ok {
-			return filler.fillSingle(rValue, value)
+		if value, ok := filler.value(node); ok {
+			return filler.fillLeaf(node, rValue, value)
 		}
 	}
 	return nil
 }
 
+// value returns value of node's key, which is told apart by segments when
+// supported.
+func (filler *nodeFiller) value(node *Node) (string, bool) {
+	if flattenMap, ok := filler.FlattenMap.(SegmentedFlattenMap); ok {
+		return flattenMap.SegmentedValue(node.Key, node.segmentsOf(node.Key))
+	}
+	return filler.FlattenMap.Value(node.Key)
+}
+
+func (filler *nodeFiller) fillLeaf(node *Node, rValue reflect.Value, value string) error {
+	if err := filler.decode(node, rValue, value); err != nil {
+		return &FillError{Key: node.Key, Err: err}
+	}
+	if err := node.check(rValue, node.Key, value); err != nil {
+		return &FillError{Key: node.Key, Err: err}
+	}
+	return nil
+}
+
+func (filler *nodeFiller) decode(node *Node, rValue reflect.Value, value string) error {
+	if node.decode != nil {
+		return node.decode(rValue, value)
+	}
+	return filler.fillSingle(rValue, value)
+}
+
 func (filler *nodeFiller) fillMap(node *Node, rValue reflect.Value) error {
 	if rValue.IsZero() {
 		rValue.Set(reflect.MakeMapWithSize(rValue.Type(), 0))
 	}
 	prefix := node.Key + util.CharDot
-	for _, key := range filler.FlattenMap.ChildrenByPrefix(prefix) {
+	keys := filler.FlattenMap.ChildrenByPrefix(prefix)
+	for _, key := range keys {
+		// Null item without keys under it is removed from map
+		if filler.isNull(prefix+key) && len(filler.FlattenMap.ChildrenByPrefix(prefix+key+util.CharDot)) == 0 {
+			rValue.SetMapIndex(reflect.ValueOf(key), reflect.Value{})
+			continue
+		}
 		child := node.Children[0].clone()
 		child.reKey(prefix, prefix+key)
 		if err := filler.fill(child); err != nil {
@@ -60,6 +204,11 @@ func (filler *nodeFiller) fillMap(node *Node, rValue reflect.Value) error {
 		}
 		rValue.SetMapIndex(reflect.ValueOf(key), child.Value)
 	}
+	if len(keys) > 0 {
+		if err := node.checkLen(rValue, node.Key); err != nil {
+			return &FillError{Key: node.Key, Err: err}
+		}
+	}
 	return nil
 }
 
@@ -67,10 +216,14 @@ func (filler *nodeFiller) fillSlice(node *Node, rValue reflect.Value) error {
 	length := rValue.Len()
 	children := make(map[int64]reflect.Value)
 	prefix := node.Key + util.CharDot
-	for _, key := range filler.FlattenMap.ChildrenByPrefix(prefix) {
+	keys := filler.FlattenMap.ChildrenByPrefix(prefix)
+	if len(keys) == 0 { // Leave slice untouched, which stays nil when unset
+		return nil
+	}
+	for _, key := range keys {
 		childIndex, err := strconv.ParseInt(key, 10, 64)
 		if err != nil {
-			return err
+			return &FillError{Key: prefix + key, Err: err}
 		}
 		if int(childIndex)+1 > length {
 			length = int(childIndex) + 1
@@ -88,16 +241,13 @@ func (filler *nodeFiller) fillSlice(node *Node, rValue reflect.Value) error {
 		newSlice.Index(int(index)).Set(value)
 	}
 	rValue.Set(newSlice)
+	if err := node.checkLen(rValue, node.Key); err != nil {
+		return &FillError{Key: node.Key, Err: err}
+	}
 	return nil
 }
 
 func (filler *nodeFiller) fillSingle(rValue reflect.Value, value string) error {
-	if rValue.CanAddr() {
-		if textUnmarshaler, ok := rValue.Addr().Interface().(encoding.TextUnmarshaler); ok {
-			return textUnmarshaler.UnmarshalText([]byte(value))
-		}
-	}
-
 	switch rValue.Kind() {
 	case reflect.String:
 		rValue.SetString(value)
@@ -3,7 +3,16 @@ package node
 import (
 	"errors"
 	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/han0110/configo/util"
 	"github.com/stretchr/testify/assert"
@@ -77,6 +86,102 @@ type EmbeddedConfig struct {
 	Slice  SliceConfig  `yaml:"slice"`
 }
 
+type Level int
+
+func (level *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*level = 0
+	case "info":
+		*level = 1
+	default:
+		return fmt.Errorf("unknown level %s", text)
+	}
+	return nil
+}
+
+type Endpoint struct {
+	Host string
+	Port int
+}
+
+func (endpoint *Endpoint) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), ":", 2)
+	port, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return err
+	}
+	endpoint.Host, endpoint.Port = parts[0], port
+	return nil
+}
+
+type Name struct {
+	First string
+	Last  string
+}
+
+func (name *Name) Decode(value string) error {
+	parts := strings.SplitN(value, " ", 2)
+	name.First, name.Last = parts[0], parts[len(parts)-1]
+	return nil
+}
+
+type Ratio struct {
+	Value float64
+}
+
+type LeafConfig struct {
+	Level       Level               `yaml:"level"`
+	Endpoint    Endpoint            `yaml:"endpoint"`
+	PtrEndpoint *Endpoint           `yaml:"ptrEndpoint"`
+	Endpoints   []Endpoint          `yaml:"endpoints"`
+	EndpointMap map[string]Endpoint `yaml:"endpointMap"`
+	Name        Name                `yaml:"name"`
+	Ratio       Ratio               `yaml:"ratio"`
+}
+
+type StdlibConfig struct {
+	Duration time.Duration   `yaml:"duration"`
+	URL      *url.URL        `yaml:"url"`
+	IP       net.IP          `yaml:"ip"`
+	IPNet    net.IPNet       `yaml:"ipNet"`
+	Regexp   *regexp.Regexp  `yaml:"regexp"`
+	FileMode os.FileMode     `yaml:"fileMode"`
+	ByteSize ByteSize        `yaml:"byteSize"`
+	BigInt   *big.Int        `yaml:"bigInt"`
+	Timeouts []time.Duration `yaml:"timeouts"`
+}
+
+type EnumConfig struct {
+	Level  string   `yaml:"level" oneof:"debug,info,warn,error"`
+	Port   int      `yaml:"port" oneof:"80, 443"`
+	Levels []string `yaml:"levels" oneof:"debug,info"`
+	Ratio  Ratio    `yaml:"ratio" oneof:"50%,100%"`
+}
+
+type ConstraintConfig struct {
+	Port     uint16            `yaml:"port" min:"1" max:"65535"`
+	PoolSize int               `yaml:"poolSize" min:"0"`
+	Ratio    float64           `yaml:"ratio" min:"0" max:"1"`
+	Timeout  time.Duration     `yaml:"timeout" min:"1s" max:"1m"`
+	Name     string            `yaml:"name" minlen:"1" maxlen:"8" pattern:"^[a-z]+$"`
+	Hosts    []string          `yaml:"hosts" minlen:"1" maxlen:"2" pattern:"^[a-z.]+$"`
+	Labels   map[string]string `yaml:"labels" maxlen:"1"`
+}
+
+var leafEncoderOption = EncoderOption{
+	DecodeHooks: map[reflect.Type]DecodeHook{
+		reflect.TypeOf(Ratio{}): func(rValue reflect.Value, value string) error {
+			val, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil {
+				return err
+			}
+			rValue.Set(reflect.ValueOf(Ratio{Value: val / 100}))
+			return nil
+		},
+	},
+}
+
 func TestWalk(t *testing.T) { // nolint: funlen
 	var result []string
 	nodeNameCallback := func(node *Node) error {
@@ -89,6 +194,7 @@ func TestWalk(t *testing.T) { // nolint: funlen
 	testcases := []struct {
 		description string
 		element     interface{}
+		option      EncoderOption
 		callback    WalkCallback
 		expected    interface{}
 		err         error
@@ -268,6 +374,21 @@ func TestWalk(t *testing.T) { // nolint: funlen
 				"slice.ptr.simple.slice",
 			},
 		},
+		{
+			description: "walk through LeafConfig",
+			element:     &LeafConfig{},
+			option:      leafEncoderOption,
+			callback:    nodeNameCallback,
+			expected: []string{
+				"level",
+				"endpoint",
+				"ptr.endpoint",
+				"endpoints",
+				"endpoint.map",
+				"name",
+				"ratio",
+			},
+		},
 		{
 			description: "walk with error",
 			element:     &SimpleConfig{},
@@ -282,7 +403,7 @@ func TestWalk(t *testing.T) { // nolint: funlen
 				result = []string{}
 			}()
 
-			root, err := New(testcase.element, EncoderOption{})
+			root, err := New(testcase.element, testcase.option)
 			require.NoError(t, err)
 
 			err = root.Walk(testcase.callback)
@@ -300,6 +421,7 @@ func TestFill(t *testing.T) { // nolint: funlen
 	testcases := []struct {
 		description string
 		element     interface{}
+		option      EncoderOption
 		keys        []string
 		data        map[string]string
 		expected    interface{}
@@ -961,11 +1083,205 @@ func TestFill(t *testing.T) { // nolint: funlen
 				},
 			},
 		},
+		{
+			description: "fill LeafConfig",
+			element:     &LeafConfig{},
+			option:      leafEncoderOption,
+			keys: []string{
+				"level",
+				"endpoint",
+				"ptr.endpoint",
+				"endpoints.0",
+				"endpoints.1",
+				"endpoint.map.a",
+				"name",
+				"ratio",
+			},
+			data: map[string]string{
+				"level":          "info",
+				"endpoint":       "localhost:80",
+				"ptr.endpoint":   "localhost:443",
+				"endpoints.0":    "a:1",
+				"endpoints.1":    "b:2",
+				"endpoint.map.a": "c:3",
+				"name":           "John Doe",
+				"ratio":          "50%",
+			},
+			expected: &LeafConfig{
+				Level:       Level(1),
+				Endpoint:    Endpoint{Host: "localhost", Port: 80},
+				PtrEndpoint: &Endpoint{Host: "localhost", Port: 443},
+				Endpoints:   []Endpoint{{Host: "a", Port: 1}, {Host: "b", Port: 2}},
+				EndpointMap: map[string]Endpoint{"a": {Host: "c", Port: 3}},
+				Name:        Name{First: "John", Last: "Doe"},
+				Ratio:       Ratio{Value: 0.5},
+			},
+		},
+		{
+			description: "fill StdlibConfig",
+			element:     &StdlibConfig{},
+			keys: []string{
+				"duration",
+				"url",
+				"ip",
+				"ip.net",
+				"regexp",
+				"file.mode",
+				"byte.size",
+				"big.int",
+				"timeouts.0",
+				"timeouts.1",
+			},
+			data: map[string]string{
+				"duration":   "1m30s",
+				"url":        "https://example.com/path?q=1",
+				"ip":         "10.0.0.1",
+				"ip.net":     "10.0.0.0/8",
+				"regexp":     "^a+$",
+				"file.mode":  "0755",
+				"byte.size":  "512MiB",
+				"big.int":    "0x10000000000000000",
+				"timeouts.0": "1s",
+				"timeouts.1": "2s",
+			},
+			expected: &StdlibConfig{
+				Duration: 90 * time.Second,
+				URL:      &url.URL{Scheme: "https", Host: "example.com", Path: "/path", RawQuery: "q=1"},
+				IP:       net.ParseIP("10.0.0.1"),
+				IPNet:    net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+				Regexp:   regexp.MustCompile("^a+$"),
+				FileMode: os.FileMode(0755),
+				ByteSize: ByteSize(512 << 20),
+				BigInt:   new(big.Int).Lsh(big.NewInt(1), 64),
+				Timeouts: []time.Duration{time.Second, 2 * time.Second},
+			},
+		},
+		{
+			description: "fill EnumConfig",
+			element:     &EnumConfig{},
+			option:      leafEncoderOption,
+			keys:        []string{"level", "port", "levels.0", "levels.1", "ratio"},
+			data: map[string]string{
+				"level":    "warn",
+				"port":     "0443",
+				"levels.0": "debug",
+				"levels.1": "info",
+				"ratio":    "100.0%",
+			},
+			expected: &EnumConfig{
+				Level:  "warn",
+				Port:   443,
+				Levels: []string{"debug", "info"},
+				Ratio:  Ratio{Value: 1},
+			},
+		},
+		{
+			description: "fill EnumConfig with error",
+			element:     &EnumConfig{},
+			keys:        []string{"level"},
+			data:        map[string]string{"level": "warning"},
+			err:         errors.New(`invalid value "warning" of level, expected one of debug, info, warn, error`),
+		},
+		{
+			description: "fill EnumConfig with error in slice",
+			element:     &EnumConfig{},
+			keys:        []string{"levels.0", "levels.1"},
+			data:        map[string]string{"levels.0": "debug", "levels.1": "trace"},
+			err:         errors.New(`invalid value "trace" of levels.1, expected one of debug, info`),
+		},
+		{
+			description: "fill ConstraintConfig",
+			element:     &ConstraintConfig{},
+			keys:        []string{"port", "pool.size", "ratio", "timeout", "name", "hosts.0", "hosts.1", "labels.a"},
+			data: map[string]string{
+				"port":      "8080",
+				"pool.size": "0",
+				"ratio":     "0.5",
+				"timeout":   "30s",
+				"name":      "configo",
+				"hosts.0":   "example.com",
+				"hosts.1":   "example.org",
+				"labels.a":  "b",
+			},
+			expected: &ConstraintConfig{
+				Port:     8080,
+				PoolSize: 0,
+				Ratio:    0.5,
+				Timeout:  30 * time.Second,
+				Name:     "configo",
+				Hosts:    []string{"example.com", "example.org"},
+				Labels:   map[string]string{"a": "b"},
+			},
+		},
+		{
+			description: "fill ConstraintConfig with error of min",
+			element:     &ConstraintConfig{},
+			keys:        []string{"port"},
+			data:        map[string]string{"port": "0"},
+			err:         errors.New(`invalid value "0" of port, expected at least 1`),
+		},
+		{
+			description: "fill ConstraintConfig with error of negative",
+			element:     &ConstraintConfig{},
+			keys:        []string{"pool.size"},
+			data:        map[string]string{"pool.size": "-1"},
+			err:         errors.New(`invalid value "-1" of pool.size, expected at least 0`),
+		},
+		{
+			description: "fill ConstraintConfig with error of max",
+			element:     &ConstraintConfig{},
+			keys:        []string{"timeout"},
+			data:        map[string]string{"timeout": "1h"},
+			err:         errors.New(`invalid value "1h" of timeout, expected at most 1m`),
+		},
+		{
+			description: "fill ConstraintConfig with error of maxlen",
+			element:     &ConstraintConfig{},
+			keys:        []string{"name"},
+			data:        map[string]string{"name": "configuration"},
+			err:         errors.New(`invalid length 13 of name, expected at most 8`),
+		},
+		{
+			description: "fill ConstraintConfig with error of pattern",
+			element:     &ConstraintConfig{},
+			keys:        []string{"hosts.0"},
+			data:        map[string]string{"hosts.0": "Example.com"},
+			err:         errors.New(`invalid value "Example.com" of hosts.0, expected to match pattern ^[a-z.]+$`),
+		},
+		{
+			description: "fill ConstraintConfig with error of maxlen of slice",
+			element:     &ConstraintConfig{},
+			keys:        []string{"hosts.0", "hosts.1", "hosts.2"},
+			data:        map[string]string{"hosts.0": "a", "hosts.1": "b", "hosts.2": "c"},
+			err:         errors.New(`invalid length 3 of hosts, expected at most 2`),
+		},
+		{
+			description: "fill StdlibConfig with error of byte size unit",
+			element:     &StdlibConfig{},
+			keys:        []string{"byte.size"},
+			data:        map[string]string{"byte.size": "16EB"},
+			err:         errors.New(`invalid byte size unit: EB`),
+		},
+		{
+			description: "fill StdlibConfig with error of byte size overflow",
+			element:     &StdlibConfig{},
+			keys:        []string{"byte.size"},
+			data:        map[string]string{"byte.size": "20000PB"},
+			err:         errors.New(`byte size 20000PB overflows`),
+		},
+		{
+			description: "fill LeafConfig with error",
+			element:     &LeafConfig{},
+			option:      leafEncoderOption,
+			keys:        []string{"level"},
+			data:        map[string]string{"level": "trace"},
+			err:         errors.New("unknown level trace"),
+		},
 	}
 
 	for _, testcase := range testcases {
 		t.Run(testcase.description, func(t *testing.T) {
-			node, err := New(testcase.element, EncoderOption{})
+			node, err := New(testcase.element, testcase.option)
 			require.NoError(t, err)
 
 			flattenMap := util.NewFlattenMap()
@@ -983,3 +1299,212 @@ func TestFill(t *testing.T) { // nolint: funlen
 		})
 	}
 }
+
+func TestSerializeValue(t *testing.T) {
+	element := &StdlibConfig{}
+	root, err := New(element, EncoderOption{})
+	require.NoError(t, err)
+
+	flattenMap := util.NewFlattenMap()
+	flattenMap.Set("duration", "1m30s")
+	flattenMap.Set("url", "https://example.com/path")
+	flattenMap.Set("ip", "10.0.0.1")
+	flattenMap.Set("ip.net", "10.0.0.0/8")
+	flattenMap.Set("regexp", "^a+$")
+	flattenMap.Set("file.mode", "0755")
+	flattenMap.Set("byte.size", "1.5GiB")
+	flattenMap.Set("big.int", "18446744073709551616")
+	flattenMap.Set("timeouts.0", "1s")
+	flattenMap.Set("timeouts.1", "2m")
+	require.NoError(t, root.FillNode(flattenMap))
+
+	result := make(map[string]string)
+	for _, n := range root.Flat() {
+		result[n.Key] = n.SerializeValue()
+	}
+	assert.Equal(t, map[string]string{
+		"duration":  "1m30s",
+		"url":       "https://example.com/path",
+		"ip":        "10.0.0.1",
+		"ip.net":    "10.0.0.0/8",
+		"regexp":    "^a+$",
+		"file.mode": "0755",
+		"byte.size": "1536MiB",
+		"big.int":   "18446744073709551616",
+		"timeouts":  "[1s,2m0s]",
+	}, result)
+}
+
+type TLSConfig struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+func (config TLSConfig) Validate() error {
+	if (config.Cert == "") != (config.Key == "") {
+		return errors.New("cert and key must both be set")
+	}
+	return nil
+}
+
+type ServerConfig struct {
+	TLS *TLSConfig `yaml:"tls"`
+}
+
+type ValidatorConfig struct {
+	TLS       TLSConfig               `yaml:"tls"`
+	Servers   []ServerConfig          `yaml:"servers"`
+	ServerMap map[string]ServerConfig `yaml:"serverMap"`
+	Name      string                  `yaml:"name"`
+}
+
+func (config *ValidatorConfig) Validate() error {
+	if config.Name == "" {
+		return errors.New("name must be set")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	testcases := []struct {
+		description string
+		element     interface{}
+		data        map[string]string
+		err         error
+	}{
+		{
+			description: "valid",
+			data:        map[string]string{"name": "test", "tls.cert": "cert", "tls.key": "key"},
+		},
+		{
+			description: "valid constraints",
+			element:     &ConstraintConfig{},
+			data:        map[string]string{"port": "80", "timeout": "1s", "name": "test", "hosts.0": "a"},
+		},
+		{
+			description: "invalid min of unset number",
+			element:     &ConstraintConfig{},
+			data:        map[string]string{"timeout": "1s", "name": "test", "hosts.0": "a"},
+			err:         errors.New(`invalid value "0" of port, expected at least 1`),
+		},
+		{
+			description: "invalid max of default",
+			element:     &ConstraintConfig{Ratio: 2},
+			data:        map[string]string{"port": "80", "timeout": "1s", "name": "test", "hosts.0": "a"},
+			err:         errors.New(`invalid value "2" of ratio, expected at most 1`),
+		},
+		{
+			description: "invalid oneof of unset string",
+			element:     &EnumConfig{},
+			data:        map[string]string{"port": "80", "ratio": "50%"},
+			err:         errors.New(`invalid value "" of level, expected one of debug, info, warn, error`),
+		},
+		{
+			description: "invalid minlen of unset string",
+			element:     &ConstraintConfig{},
+			data:        map[string]string{"port": "80", "timeout": "1s", "hosts.0": "a"},
+			err:         errors.New("invalid length 0 of name, expected at least 1"),
+		},
+		{
+			description: "invalid minlen of unset slice",
+			element:     &ConstraintConfig{},
+			data:        map[string]string{"port": "80", "timeout": "1s", "name": "test"},
+			err:         errors.New("invalid length 0 of hosts, expected at least 1"),
+		},
+		{
+			description: "invalid parent",
+			data:        map[string]string{},
+			err:         errors.New("name must be set"),
+		},
+		{
+			description: "invalid nested first",
+			data:        map[string]string{"tls.cert": "cert"},
+			err:         errors.New("tls: cert and key must both be set"),
+		},
+		{
+			description: "invalid nested in slice",
+			data:        map[string]string{"name": "test", "servers.0.tls.cert": "cert"},
+			err:         errors.New("servers.0.tls: cert and key must both be set"),
+		},
+		{
+			description: "invalid nested in map",
+			data:        map[string]string{"name": "test", "server.map.a.tls.key": "key"},
+			err:         errors.New("server.map.a.tls: cert and key must both be set"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			element := testcase.element
+			if element == nil {
+				element = &ValidatorConfig{}
+			}
+			node, err := New(element, EncoderOption{})
+			require.NoError(t, err)
+
+			flattenMap := util.NewFlattenMap()
+			for key, value := range testcase.data {
+				flattenMap.Set(key, value)
+			}
+			require.NoError(t, node.FillNode(flattenMap))
+
+			err = node.Validate()
+			if testcase.err != nil {
+				require.EqualError(t, err, testcase.err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewWithInvalidTags(t *testing.T) {
+	testcases := []struct {
+		description string
+		element     interface{}
+		err         error
+	}{
+		{
+			description: "pattern of int",
+			element: &struct {
+				Port int `yaml:"port" pattern:"^[0-9]+$"`
+			}{},
+			err: errors.New("invalid pattern tag of port, expected string but got int"),
+		},
+		{
+			description: "pattern of item of int slice",
+			element: &struct {
+				Ports []int `yaml:"ports" pattern:"^[0-9]+$"`
+			}{},
+			err: errors.New("invalid pattern tag of ports, expected string but got int"),
+		},
+		{
+			description: "min of string",
+			element: &struct {
+				Name string `yaml:"name" min:"a"`
+			}{},
+			err: errors.New("invalid min tag of name: unable to compare value of kind string"),
+		},
+		{
+			description: "max not decoded",
+			element: &struct {
+				Port int `yaml:"port" max:"high"`
+			}{},
+			err: errors.New(`invalid max tag of port: strconv.ParseInt: parsing "high": invalid syntax`),
+		},
+		{
+			description: "minlen of int",
+			element: &struct {
+				Port int `yaml:"port" minlen:"1"`
+			}{},
+			err: errors.New("invalid length tag of port, expected string, slice or map but got int"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			_, err := New(testcase.element, EncoderOption{})
+			require.EqualError(t, err, testcase.err.Error())
+		})
+	}
+}
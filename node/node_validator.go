@@ -0,0 +1,87 @@
+package node
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/han0110/configo/util"
+	"github.com/pkg/errors"
+)
+
+// Validator is implemented by types which validate themselves after loading,
+// which is useful for rules across fields.
+type Validator interface {
+	Validate() error
+}
+
+// Validate checks constraints of node's value and all nested values, and
+// calls Validate on the ones which implement Validator, nested first then
+// parent, and wraps errors with key.
+func (node *Node) Validate() error {
+	return node.validate(node.Value, node.Key)
+}
+
+func (node *Node) validate(rValue reflect.Value, key string) error {
+	if rValue.Kind() == reflect.Ptr {
+		if rValue.IsNil() {
+			return nil
+		}
+		rValue = rValue.Elem()
+	}
+
+	if err := node.checkFinal(rValue, key); err != nil {
+		return err
+	}
+
+	switch {
+	case node.decode != nil:
+	case node.isDynamic():
+		if err := node.validateDynamic(rValue, key); err != nil {
+			return err
+		}
+	default:
+		for _, child := range node.Children {
+			childKey := key + child.Key[len(node.Key):]
+			if err := child.validate(rValue.FieldByName(child.FiledName), childKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	if validator, ok := pointerTo(rValue).Interface().(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			if key == "" {
+				return err
+			}
+			return errors.Wrap(err, key)
+		}
+	}
+
+	return nil
+}
+
+func (node *Node) validateDynamic(rValue reflect.Value, key string) error {
+	child := node.Children[0]
+
+	switch rValue.Kind() {
+	case reflect.Slice:
+		for i := 0; i < rValue.Len(); i++ {
+			childKey := key + util.CharDot + strconv.Itoa(i)
+			if err := child.validate(rValue.Index(i), childKey); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		mapKeys := rValue.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool { return mapKeys[i].String() < mapKeys[j].String() })
+		for _, mapKey := range mapKeys {
+			childKey := key + util.CharDot + mapKey.String()
+			if err := child.validate(rValue.MapIndex(mapKey), childKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
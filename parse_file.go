@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -13,63 +14,161 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// ParseFile parses files into a map.
+const (
+	// optionalPrefix defines prefix of path of optional config file, which is
+	// skipped when it does not exist.
+	optionalPrefix = "?"
+	// includeKey defines key of path or list of paths of files included by
+	// config file, which are relative to the including file.
+	includeKey = "$include"
+)
+
+// ParseFile parses files into a map, where source of each key is the path
+// of file it comes from, and path prefixed by ? like ?/etc/app/local.yaml is
+// optional. Files could include others by $include, which are flattened
+// before own keys. Values null, ~, empty value or tagged by !!null are set to
+// explicit null, and [] or {} are set to explicit empty.
 func ParseFile(filepaths []string) (*util.FlattenMap, error) {
+	return ParseFileWithProfiles(filepaths, Profiles{})
+}
+
+// Profiles defines active profiles of config files.
+type Profiles struct {
+	// Key is key of sections of profiles in file like profiles.<profile>,
+	// which is not recognized when empty.
+	Key string
+	// Active profiles are applied in order.
+	Active []string
+}
+
+// ParseFileWithProfiles parses files into a map like ParseFile, where each
+// file like config.yaml is overlaid by optional config.<profile>.yaml, and
+// section of active profile in file is merged after its own keys.
+func ParseFileWithProfiles(filepaths []string, profiles Profiles) (*util.FlattenMap, error) {
 	if len(filepaths) == 0 {
 		return util.NewFlattenMap(), nil
 	}
 
 	flattenMap := util.NewFlattenMap()
 	for _, path := range filepaths {
-		var flattener flattener
-
+		path, optional := optionalPath(path)
 		if path == "" {
 			return nil, errors.New("expected config file path, but got empty string")
 		}
 
-		// Check whether file extension is supported
-		switch strings.ToLower(filepath.Ext(path)) {
-		case ".yaml", ".yml":
-			flattener = &yamlFlattener{}
-		// TODO: Support json with ordered map library
-		// case ".json":
-		case "":
-			return nil, errors.Errorf(
-				"unrecognized config file extension, file: %s",
-				path,
-			)
-		default:
-			return nil, errors.Errorf(
-				"unsupported config file extension: %s",
-				filepath.Ext(path),
-			)
-		}
-
-		// Read file
-		data, err := ioutil.ReadFile(filepath.Clean(path))
-		if err != nil {
+		fileMap := util.NewFlattenMap()
+		if err := flattenFile(fileMap, path, optional, nil, &profiles); err != nil {
 			return nil, err
 		}
+		flattenMap.Merge(fileMap, path)
 
-		if err := flattener.Flatten(flattenMap, data); err != nil {
-			return nil, err
+		for _, profile := range profiles.Active {
+			ext := filepath.Ext(path)
+			overlayPath := path[:len(path)-len(ext)] + "." + profile + ext
+			overlayMap := util.NewFlattenMap()
+			if err := flattenFile(overlayMap, overlayPath, true, nil, &profiles); err != nil {
+				return nil, err
+			}
+			flattenMap.Merge(overlayMap, overlayPath)
 		}
 	}
 
 	return flattenMap, nil
 }
 
+// flattenFile flattens file into setter, where including are paths of files
+// including it to detect cycle.
+func flattenFile(setter setter, path string, optional bool, including []string, profiles *Profiles) error {
+	var flattener flattener
+
+	// Check whether file extension is supported
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		flattener = &yamlFlattener{path: path, including: including, profiles: profiles}
+	// TODO: Support json with ordered map library
+	// case ".json":
+	case "":
+		return errors.Errorf(
+			"unrecognized config file extension, file: %s",
+			path,
+		)
+	default:
+		return errors.Errorf(
+			"unsupported config file extension: %s",
+			filepath.Ext(path),
+		)
+	}
+
+	// Read file
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	switch {
+	case os.IsNotExist(err) && optional:
+		return nil
+	case os.IsNotExist(err):
+		return errors.Errorf("config file %s does not exist", path)
+	case err != nil:
+		return errors.Wrapf(err, "read config file %s", path)
+	}
+
+	return flattener.Flatten(setter, data)
+}
+
+// optionalPath trims prefix ? of optional path.
+func optionalPath(path string) (string, bool) {
+	if strings.HasPrefix(path, optionalPrefix) {
+		return path[len(optionalPrefix):], true
+	}
+	return path, false
+}
+
+// isSupportedFile checks whether extension of file is supported by ParseFile.
+func isSupportedFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+type setter interface {
+	Set(key, value string)
+	SetNull(key string)
+	SetEmpty(key string)
+}
+
+// prefixSetter sets key under prefix, for file included under a key.
+type prefixSetter struct {
+	setter
+	prefix string
+}
+
+func (s *prefixSetter) Set(key, value string) {
+	s.setter.Set(s.prefix+util.CharDot+key, value)
+}
+
+func (s *prefixSetter) SetNull(key string) {
+	s.setter.SetNull(s.prefix + util.CharDot + key)
+}
+
+func (s *prefixSetter) SetEmpty(key string) {
+	s.setter.SetEmpty(s.prefix + util.CharDot + key)
+}
+
 type flattener interface {
-	Flatten(*util.FlattenMap, []byte) error
+	Flatten(setter, []byte) error
 }
 
 type yamlFlattener struct {
+	path      string
+	including []string
+	profiles  *Profiles
 }
 
-func (flattener *yamlFlattener) Flatten(flattenMap *util.FlattenMap, data []byte) error {
+func (flattener *yamlFlattener) Flatten(setter setter, data []byte) error {
 	dec := yaml.NewDecoder(bytes.NewReader([]byte(data)))
 	for {
-		err := dec.Decode(&yamlCursor{setter: flattenMap})
+		err := dec.Decode(&yamlCursor{flattener: flattener, setter: setter})
 		if err == nil {
 			continue
 		}
@@ -81,14 +180,70 @@ func (flattener *yamlFlattener) Flatten(flattenMap *util.FlattenMap, data []byte
 	return nil
 }
 
-type yamlCursor struct {
-	setter interface {
-		Set(key, value string)
+// include flattens files included by paths relative to the including file,
+// and prefixed by ? for optional ones, into setter under key.
+func (flattener *yamlFlattener) include(setter setter, key string, paths []string) error {
+	including := append(append([]string{}, flattener.including...), flattener.path)
+	if key != "" {
+		setter = &prefixSetter{setter: setter, prefix: key}
+	}
+	for _, path := range paths {
+		path, optional := optionalPath(path)
+		if path == "" {
+			return errors.Errorf("expected included file path in %s, but got empty string", flattener.path)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(flattener.path), path)
+		}
+		for _, includingPath := range including {
+			if sameFile(includingPath, path) {
+				return errors.Errorf("config file %s includes itself", path)
+			}
+		}
+		if err := flattenFile(setter, path, optional, including, flattener.profiles); err != nil {
+			return errors.Wrapf(err, "include in %s", flattener.path)
+		}
+	}
+	return nil
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
 	}
-	key string
+	return absA == absB
+}
+
+const (
+	// yamlMergeKey defines key of mappings merged into the mapping.
+	yamlMergeKey = "<<"
+	// yamlMergeTag defines tag of merge key.
+	yamlMergeTag = "!!merge"
+	// yamlNullTag defines tag of null like null, ~ or empty value.
+	yamlNullTag = "!!null"
+)
+
+type yamlCursor struct {
+	flattener *yamlFlattener
+	setter    setter
+	key       string
+	// aliases are anchored nodes being expanded to detect cycle.
+	aliases []*yaml.Node
 }
 
 func (cur *yamlCursor) UnmarshalYAML(node *yaml.Node) error {
+	// Null and empty list or mapping clear value set by former files or sources
+	if cur.key != "" && isYAMLNull(node) {
+		cur.setter.SetNull(cur.key)
+		return nil
+	}
+	if cur.key != "" && isYAMLEmpty(node) {
+		cur.setter.SetEmpty(cur.key)
+		return nil
+	}
+
 	switch node.Kind {
 	case yaml.SequenceNode:
 		for i := range node.Content {
@@ -97,23 +252,142 @@ func (cur *yamlCursor) UnmarshalYAML(node *yaml.Node) error {
 			if cur.key != "" {
 				key = cur.key + util.CharDot + key
 			}
-			if err := (&yamlCursor{setter: cur.setter, key: key}).UnmarshalYAML(itemNode); err != nil {
+			if err := cur.child(key).UnmarshalYAML(itemNode); err != nil {
 				return err
 			}
 		}
 	case yaml.MappingNode:
+		// Included files and merged mappings are flattened before own keys
+		for i := 0; i < len(node.Content); i += 2 {
+			if keyNode, valNode := node.Content[i], node.Content[i+1]; keyNode.Value == includeKey {
+				if err := cur.include(valNode); err != nil {
+					return err
+				}
+			}
+		}
+		for i := 0; i < len(node.Content); i += 2 {
+			if keyNode, valNode := node.Content[i], node.Content[i+1]; isYAMLMerge(keyNode) {
+				if err := cur.merge(valNode); err != nil {
+					return err
+				}
+			}
+		}
 		for i := 0; i < len(node.Content); i += 2 {
 			keyNode, valNode := node.Content[i], node.Content[i+1]
+			if keyNode.Value == includeKey || isYAMLMerge(keyNode) || cur.isProfiles(keyNode.Value) {
+				continue
+			}
 			key := keyNode.Value
 			if cur.key != "" {
 				key = cur.key + util.CharDot + key
 			}
-			if err := (&yamlCursor{setter: cur.setter, key: key}).UnmarshalYAML(valNode); err != nil {
+			if err := cur.child(key).UnmarshalYAML(valNode); err != nil {
 				return err
 			}
 		}
+		// Sections of active profiles are flattened after own keys
+		for i := 0; i < len(node.Content); i += 2 {
+			if keyNode, valNode := node.Content[i], node.Content[i+1]; cur.isProfiles(keyNode.Value) {
+				if err := cur.applyProfiles(valNode); err != nil {
+					return err
+				}
+			}
+		}
 	case yaml.ScalarNode:
 		cur.setter.Set(cur.key, node.Value)
+	case yaml.AliasNode:
+		for _, alias := range cur.aliases {
+			if alias == node.Alias {
+				return errors.Errorf("anchor %s in %s contains itself", node.Value, cur.flattener.path)
+			}
+		}
+		aliased := cur.child(cur.key)
+		aliased.aliases = append(aliased.aliases, node.Alias)
+		return aliased.UnmarshalYAML(node.Alias)
+	}
+	return nil
+}
+
+// merge flattens mappings merged by merge key, which is a mapping or a list of
+// mappings, where former mapping in list overrides latter one.
+func (cur *yamlCursor) merge(node *yaml.Node) error {
+	mappings := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		mappings = node.Content
+	}
+	for i := len(mappings) - 1; i >= 0; i-- {
+		mapping := mappings[i]
+		for mapping.Kind == yaml.AliasNode {
+			mapping = mapping.Alias
+		}
+		if mapping.Kind != yaml.MappingNode {
+			return errors.Errorf("invalid %s in %s, expected mapping or list of mappings", yamlMergeKey, cur.flattener.path)
+		}
+		if err := cur.child(cur.key).UnmarshalYAML(mappings[i]); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+func isYAMLMerge(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Value == yamlMergeKey && node.ShortTag() == yamlMergeTag
+}
+
+// isYAMLNull checks whether node is null, ~, empty value or tagged by !!null.
+func isYAMLNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.ShortTag() == yamlNullTag
+}
+
+// isYAMLEmpty checks whether node is an empty list or mapping.
+func isYAMLEmpty(node *yaml.Node) bool {
+	return (node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode) && len(node.Content) == 0
+}
+
+// isProfiles checks whether key is key of sections of profiles at root.
+func (cur *yamlCursor) isProfiles(key string) bool {
+	profiles := cur.flattener.profiles
+	return cur.key == "" && profiles != nil && profiles.Key != "" && key == profiles.Key
+}
+
+// applyProfiles flattens sections of active profiles in node at root.
+func (cur *yamlCursor) applyProfiles(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return errors.Errorf("invalid %s in %s, expected sections of profiles", cur.flattener.profiles.Key, cur.flattener.path)
+	}
+	for _, profile := range cur.flattener.profiles.Active {
+		for i := 0; i < len(node.Content); i += 2 {
+			if keyNode, valNode := node.Content[i], node.Content[i+1]; keyNode.Value == profile {
+				if err := cur.child("").UnmarshalYAML(valNode); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (cur *yamlCursor) child(key string) *yamlCursor {
+	aliases := append([]*yaml.Node{}, cur.aliases...)
+	return &yamlCursor{flattener: cur.flattener, setter: cur.setter, key: key, aliases: aliases}
+}
+
+// include flattens files included by paths in node, which is a path or a list
+// of paths.
+func (cur *yamlCursor) include(node *yaml.Node) error {
+	var paths []string
+	switch node.Kind {
+	case yaml.ScalarNode:
+		paths = []string{node.Value}
+	case yaml.SequenceNode:
+		for _, itemNode := range node.Content {
+			if itemNode.Kind != yaml.ScalarNode {
+				return errors.Errorf("invalid %s in %s, expected path or list of paths", includeKey, cur.flattener.path)
+			}
+			paths = append(paths, itemNode.Value)
+		}
+	default:
+		return errors.Errorf("invalid %s in %s, expected path or list of paths", includeKey, cur.flattener.path)
+	}
+	return cur.flattener.include(cur.setter, cur.key, paths)
+}
@@ -1,6 +1,7 @@
 package configo
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -163,6 +164,39 @@ func TestParse(t *testing.T) {
 				"-65535.00390625",
 			},
 		},
+		{
+			description:    "include",
+			filepaths:      []string{"./fixtures/include/service.yaml"},
+			expectedKeys:   []string{"level", "port", "logging.format", "logging.level"},
+			expectedValues: []string{"info", "8080", "json", "debug"},
+		},
+		{
+			description: "anchor",
+			filepaths:   []string{"./fixtures/anchor.yaml"},
+			expectedKeys: []string{
+				"defaults.level", "defaults.port", "tls.enabled",
+				"services.api.level", "services.api.port",
+				"services.worker.port", "services.worker.enabled", "services.worker.level",
+				"hosts.0", "hosts.1", "backup.hosts.0", "backup.hosts.1",
+			},
+			expectedValues: []string{
+				"info", "80", "true",
+				"info", "8080",
+				"80", "true", "debug",
+				"a", "b", "a", "b",
+			},
+		},
+		{
+			description: "anchor cycle",
+			filepaths:   []string{"./fixtures/anchor_cycle.yaml"},
+			err:         errors.New("anchor a in ./fixtures/anchor_cycle.yaml contains itself"),
+		},
+		{
+			description: "include cycle",
+			filepaths:   []string{"./fixtures/include/cycle_a.yaml"},
+			err: errors.New("include in ./fixtures/include/cycle_a.yaml: " +
+				"config file fixtures/include/cycle_a.yaml includes itself"),
+		},
 	}
 
 	for _, testcase := range testcases {
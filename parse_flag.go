@@ -2,27 +2,38 @@ package configo
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
+	"github.com/han0110/configo/node"
 	"github.com/han0110/configo/util"
 )
 
-// ParseFlag parses arguments into a map.
+const (
+	// negationPrefix defines prefix of flag to negate a boolean flag.
+	negationPrefix = "no-"
+)
+
+var numberRegexp = regexp.MustCompile(`^-?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+// ParseFlag parses arguments into a map, which stops at the first positional
+// argument.
 func ParseFlag(args []string) (*util.FlattenMap, error) {
-	f := flagSet{
-		args:        args,
-		values:      make(map[string]string),
-		sliceValues: make(map[string][]string),
-	}
+	flattenMap, _, err := ParseFlagWithNode(args, nil)
+	return flattenMap, err
+}
 
-	for {
-		seen, err := f.parseOne()
-		if seen {
-			continue
-		}
-		if err == nil {
-			break
-		}
-		return nil, err
+// ParseFlagWithNode parses arguments into a map and returns positional
+// arguments, which consults node to parse boolean flags which never take next
+// argument as value, negated boolean flags like --no-foo, and combined short
+// flags like -abc, and sets empty map or slice flags like --hosts= or
+// --hosts=[] to explicit empty. Flags stop at the first positional argument,
+// unless any node is tagged with arg or args to take positional arguments
+// interspersed with flags.
+func ParseFlagWithNode(args []string, n *node.Node) (*util.FlattenMap, []string, error) {
+	f := newFlagSet(args, n)
+	if err := f.parse(); err != nil {
+		return nil, nil, err
 	}
 
 	flattenMap := util.NewFlattenMap()
@@ -34,17 +45,58 @@ func ParseFlag(args []string) (*util.FlattenMap, error) {
 			}
 			continue
 		}
+		if f.isEmpty(key) {
+			flattenMap.SetEmpty(key)
+			continue
+		}
 		flattenMap.Set(key, f.values[key])
 	}
 
-	return flattenMap, nil
+	return flattenMap, f.positionals, nil
 }
 
 type flagSet struct {
-	args        []string
-	keys        []string
-	values      map[string]string
-	sliceValues map[string][]string
+	node *node.Node
+	// interspersed parses flags after positional arguments, otherwise flags
+	// stop at the first positional argument.
+	interspersed      bool
+	numArgs           int
+	args              []string
+	positionals       []string
+	positionalIndexes []int
+	keys              []string
+	values            map[string]string
+	sliceValues       map[string][]string
+}
+
+func newFlagSet(args []string, n *node.Node) *flagSet {
+	return &flagSet{
+		node:         n,
+		interspersed: hasPositionalTags(n),
+		numArgs:      len(args),
+		args:         args,
+		values:       make(map[string]string),
+		sliceValues:  make(map[string][]string),
+	}
+}
+
+func (f *flagSet) parse() error {
+	for {
+		seen, err := f.parseOne()
+		if seen {
+			continue
+		}
+		return err
+	}
+}
+
+// shiftPositionals shifts count arguments as positional arguments.
+func (f *flagSet) shiftPositionals(count int) {
+	for i := 0; i < count; i++ {
+		f.positionalIndexes = append(f.positionalIndexes, f.numArgs-len(f.args))
+		f.positionals = append(f.positionals, f.args[0])
+		f.args = f.args[1:]
+	}
 }
 
 func (f *flagSet) parseOne() (bool, error) {
@@ -54,13 +106,19 @@ func (f *flagSet) parseOne() (bool, error) {
 
 	arg := f.args[0]
 	if len(arg) < 2 || arg[0] != '-' {
-		return false, nil
+		if !f.interspersed { // Positional argument terminates the flags
+			f.shiftPositionals(len(f.args))
+			return false, nil
+		}
+		f.shiftPositionals(1) // Positional argument interspersed with flags
+		return true, nil
 	}
 	numMinuses := 1
 	if arg[1] == '-' {
 		numMinuses = 2
 		if arg == "--" { // "--" terminates the flags
 			f.args = f.args[1:]
+			f.shiftPositionals(len(f.args))
 			return false, nil
 		}
 	}
@@ -71,34 +129,134 @@ func (f *flagSet) parseOne() (bool, error) {
 	}
 
 	// It's a flag. Does it have an argument?
+	flag := f.args[0]
 	f.args = f.args[1:]
+	name, value, hasValue := arg, "", false
 	for i := 1; i < len(arg); i++ { // '=' will not be first
 		if arg[i] == '=' {
-			f.setValue(arg[0:i], arg[i+1:])
-			return true, nil
+			name, value, hasValue = arg[0:i], arg[i+1:], true
+			break
+		}
+	}
+
+	// Expand combined short flags
+	parse := f.parseFlag
+	if numMinuses == 1 && len(name) > 1 && f.isShortFlags(name) {
+		parse = f.parseShortFlags
+	}
+	if err := parse(flag, name, value, hasValue); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (f *flagSet) parseFlag(flag, name, value string, hasValue bool) error {
+	if hasValue {
+		f.setValue(name, value)
+		return nil
+	}
+
+	// Boolean flag never takes next argument as value
+	n := f.find(name)
+	if n != nil && n.IsBool() {
+		f.setValue(name, "true")
+		return nil
+	}
+	if n == nil && strings.HasPrefix(name, negationPrefix) {
+		if negated := f.find(name[len(negationPrefix):]); negated != nil && negated.IsBool() {
+			f.setValue(name[len(negationPrefix):], "false")
+			return nil
 		}
 	}
 
 	// End of the arguments, consider it to be a boolean flag
 	if len(f.args) == 0 {
-		f.setValue(arg, "true")
-		return true, nil
+		if n != nil {
+			return fmt.Errorf("flag needs an argument: %s", flag)
+		}
+		f.setValue(name, "true")
+		return nil
 	}
 
 	// Looking for next argument
-	nextArg, value := f.args[0], ""
+	nextArg := f.args[0]
 	if len(nextArg) == 0 {
-		value = ""
-	} else if nextArg[0] == '-' {
+		value, f.args = "", f.args[1:]
+	} else if nextArg[0] == '-' && nextArg != "-" && !numberRegexp.MatchString(nextArg) {
 		// Start with '-', consider it to be another flag's arg,
 		// and current flag to be a boolean flag
+		if n != nil {
+			return fmt.Errorf("flag needs an argument: %s", flag)
+		}
 		value = "true"
 	} else {
 		// Take the next argument as value, then shift.
 		value, f.args = nextArg, f.args[1:]
 	}
-	f.setValue(arg, value)
-	return true, nil
+	f.setValue(name, value)
+	return nil
+}
+
+// isShortFlags checks whether name is combined short flags, which is not a
+// known flag but starts with a known short flag.
+func (f *flagSet) isShortFlags(name string) bool {
+	return f.node != nil && f.find(name) == nil && f.find(name[:1]) != nil
+}
+
+// parseShortFlags parses combined short flags like -abc as -a -b -c, where
+// the first non-boolean flag takes the rest as value like -ofile.
+func (f *flagSet) parseShortFlags(flag, name, value string, hasValue bool) error {
+	for i := 0; i < len(name); i++ {
+		short := name[i : i+1]
+		if n := f.find(short); n != nil && !n.IsBool() && i+1 < len(name) {
+			if hasValue {
+				return fmt.Errorf("bad flag syntax: %s", flag)
+			}
+			f.setValue(short, name[i+1:])
+			return nil
+		}
+		if i+1 == len(name) {
+			return f.parseFlag(flag, short, value, hasValue)
+		}
+		f.setValue(short, "true")
+	}
+	return nil
+}
+
+// isEmpty checks whether flag of map or slice is set to empty like --hosts=
+// or --hosts=[], which clears it.
+func (f *flagSet) isEmpty(name string) bool {
+	if n := f.find(name); n == nil || !n.IsDynamic() {
+		return false
+	}
+	switch f.values[name] {
+	case "", "[]", "{}":
+		return true
+	default:
+		return false
+	}
+}
+
+// hasPositionalTags checks whether any node is tagged with arg or args.
+func hasPositionalTags(n *node.Node) bool {
+	if n == nil {
+		return false
+	}
+	tagged := false
+	_ = n.Walk(func(child *node.Node) error {
+		tagged = tagged || child.Arg != ""
+		return nil
+	})
+	return tagged
+}
+
+// find finds node of flag, or nil when node is not provided.
+func (f *flagSet) find(name string) *node.Node {
+	if f.node == nil {
+		return nil
+	}
+	return f.node.Find(util.ToDotCase(name))
 }
 
 func (f *flagSet) setValue(key, value string) {
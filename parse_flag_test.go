@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/han0110/configo/node"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -63,6 +64,12 @@ func TestParseFlag(t *testing.T) {
 			args:           []string{"foo", "bar", "baz"},
 			expectedValues: []string{},
 		},
+		{
+			description:    "stop at positional arguments",
+			args:           []string{"--foo", "bar", "baz", "--cat", "dog"},
+			expectedKeys:   []string{"foo"},
+			expectedValues: []string{"bar"},
+		},
 		{
 			description: "bad syntax",
 			args:        []string{"---foo", "baz"},
@@ -90,3 +97,105 @@ func TestParseFlag(t *testing.T) {
 		})
 	}
 }
+
+type flagConfig struct {
+	Verbose  bool            `yaml:"verbose"`
+	A        bool            `yaml:"a"`
+	B        bool            `yaml:"b"`
+	O        string          `yaml:"o"`
+	Offset   int             `yaml:"offset"`
+	Ratio    float64         `yaml:"ratio"`
+	Features map[string]bool `yaml:"features"`
+	Files    []string        `yaml:"files" args:"rest"`
+}
+
+func TestParseFlagWithNode(t *testing.T) {
+	testcases := []struct {
+		description    string
+		args           []string
+		expectedKeys   []string
+		expectedValues []string
+		expectedArgs   []string
+		err            error
+	}{
+		{
+			description:    "boolean never takes value",
+			args:           []string{"--verbose", "run", "--offset", "1"},
+			expectedKeys:   []string{"verbose", "offset"},
+			expectedValues: []string{"true", "1"},
+			expectedArgs:   []string{"run"},
+		},
+		{
+			description:    "positional arguments",
+			args:           []string{"a", "--offset", "1", "b", "--", "--verbose"},
+			expectedKeys:   []string{"offset"},
+			expectedValues: []string{"1"},
+			expectedArgs:   []string{"a", "b", "--verbose"},
+		},
+		{
+			description:    "boolean with explicit value",
+			args:           []string{"--verbose=false", "--features-foo", "--features-bar=false"},
+			expectedKeys:   []string{"verbose", "features.foo", "features.bar"},
+			expectedValues: []string{"false", "true", "false"},
+		},
+		{
+			description:    "negated boolean",
+			args:           []string{"--no-verbose", "--no-features-foo"},
+			expectedKeys:   []string{"verbose", "features.foo"},
+			expectedValues: []string{"false", "false"},
+		},
+		{
+			description:    "combined short flags",
+			args:           []string{"-ab"},
+			expectedKeys:   []string{"a", "b"},
+			expectedValues: []string{"true", "true"},
+		},
+		{
+			description:    "combined short flags with value",
+			args:           []string{"-abo", "file"},
+			expectedKeys:   []string{"a", "b", "o"},
+			expectedValues: []string{"true", "true", "file"},
+		},
+		{
+			description:    "short flag with attached value",
+			args:           []string{"-ofile"},
+			expectedKeys:   []string{"o"},
+			expectedValues: []string{"file"},
+		},
+		{
+			description:    "negative numbers",
+			args:           []string{"--offset", "-5", "--ratio", "-.5"},
+			expectedKeys:   []string{"offset", "ratio"},
+			expectedValues: []string{"-5", "-.5"},
+		},
+		{
+			description: "missing argument",
+			args:        []string{"--offset", "--verbose"},
+			err:         errors.New("flag needs an argument: --offset"),
+		},
+		{
+			description: "missing argument in the end",
+			args:        []string{"--verbose", "-o"},
+			err:         errors.New("flag needs an argument: -o"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			n, err := node.New(&flagConfig{}, node.EncoderOption{})
+			require.NoError(t, err)
+
+			result, args, err := ParseFlagWithNode(testcase.args, n)
+
+			if testcase.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, testcase.expectedKeys, result.Keys())
+				assert.Equal(t, testcase.expectedValues, result.Values())
+				assert.Equal(t, testcase.expectedArgs, args)
+			} else {
+				require.NotNil(t, err)
+				require.EqualError(t, testcase.err, err.Error())
+			}
+		})
+	}
+}
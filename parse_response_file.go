@@ -0,0 +1,154 @@
+package configo
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/han0110/configo/node"
+	"github.com/han0110/configo/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	// responseFilePrefix defines prefix of argument which is a response file.
+	responseFilePrefix = "@"
+	// responseFileComment defines prefix of comment in response file.
+	responseFileComment = "#"
+)
+
+// ExpandResponseFiles expands arguments like @args.txt into arguments read
+// from the file, which has one flag per line like "--foo bar" or "--foo=bar",
+// and ignores empty lines and comments starting with # at line start or after
+// whitespace. Argument starting with @@ is unescaped into @, and arguments
+// after "--" or taken as value of preceding flag like --cert @cert.pem are not
+// expanded. Response files in a response file are relative to it.
+func ExpandResponseFiles(args []string) ([]string, error) {
+	return ExpandResponseFilesWithNode(args, nil)
+}
+
+// ExpandResponseFilesWithNode expands response files like ExpandResponseFiles,
+// which consults node to know boolean flags which never take next argument as
+// value like ParseFlagWithNode.
+func ExpandResponseFilesWithNode(args []string, n *node.Node) ([]string, error) {
+	return expandResponseFiles(args, n, "", make(map[string]bool))
+}
+
+func expandResponseFiles(args []string, n *node.Node, dir string, visiting map[string]bool) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+	isValue := false
+	for i, arg := range args {
+		if isValue {
+			expanded, isValue = append(expanded, arg), false
+			continue
+		}
+		switch {
+		case arg == "--":
+			return append(expanded, args[i:]...), nil
+		case strings.HasPrefix(arg, responseFilePrefix+responseFilePrefix):
+			expanded = append(expanded, arg[1:])
+		case strings.HasPrefix(arg, responseFilePrefix):
+			path := arg[1:]
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			if visiting[path] {
+				return nil, errors.Errorf("response file %s includes itself", path)
+			}
+
+			fileArgs, err := readResponseFile(path)
+			if err != nil {
+				return nil, err
+			}
+
+			visiting[path] = true
+			fileArgs, err = expandResponseFiles(fileArgs, n, filepath.Dir(path), visiting)
+			if err != nil {
+				return nil, err
+			}
+			delete(visiting, path)
+
+			expanded = append(expanded, fileArgs...)
+		default:
+			expanded, isValue = append(expanded, arg), takesValue(arg, n)
+		}
+	}
+	return expanded, nil
+}
+
+// takesValue checks whether argument is a flag taking next argument as value,
+// which has no value after = and is not boolean.
+func takesValue(arg string, n *node.Node) bool {
+	if len(arg) < 2 || arg[0] != '-' || strings.Contains(arg, "=") || numberRegexp.MatchString(arg) {
+		return false
+	}
+	name := strings.TrimLeft(arg, "-")
+	if n == nil || name == "" {
+		return name != ""
+	}
+	if found := n.Find(util.ToDotCase(name)); found != nil {
+		return !found.IsBool()
+	}
+	if strings.HasPrefix(name, negationPrefix) {
+		if negated := n.Find(util.ToDotCase(name[len(negationPrefix):])); negated != nil && negated.IsBool() {
+			return false
+		}
+	}
+	return true
+}
+
+func readResponseFile(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read response file")
+	}
+
+	var args []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		// Split flag and its value separated by whitespace
+		if strings.HasPrefix(line, "-") {
+			if index := strings.IndexAny(line, " \t"); index > 0 && !strings.Contains(line[:index], "=") {
+				args = append(args, line[:index], unquote(strings.TrimSpace(line[index:])))
+				continue
+			}
+		}
+		args = append(args, line)
+	}
+
+	return args, scanner.Err()
+}
+
+// stripComment removes comment starting with # at line start or after
+// whitespace, which is not quoted.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(line[i:], responseFileComment) && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote removes a pair of surrounding quotes of str.
+func unquote(str string) string {
+	if len(str) >= 2 && (str[0] == '"' || str[0] == '\'') && str[len(str)-1] == str[0] {
+		return str[1 : len(str)-1]
+	}
+	return str
+}
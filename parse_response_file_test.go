@@ -0,0 +1,103 @@
+package configo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandResponseFiles(t *testing.T) {
+	testcases := []struct {
+		description string
+		args        []string
+		expected    []string
+		err         error
+	}{
+		{
+			description: "nested",
+			args:        []string{"--foo", "bar", "@./fixtures/flags.txt", "output.csv"},
+			expected: []string{
+				"--foo", "bar",
+				"--level", "debug",
+				"--name", "batch job #1",
+				"--hosts=a",
+				"--hosts", "b",
+				"-v",
+				"input.csv",
+				"output.csv",
+			},
+		},
+		{
+			description: "values of flags",
+			args:        []string{"--cert", "@./fixtures/cert.pem", "--name=@name", "-v", "@@v"},
+			expected:    []string{"--cert", "@./fixtures/cert.pem", "--name=@name", "-v", "@@v"},
+		},
+		{
+			description: "escaped and terminated",
+			args:        []string{"@@foo", "--", "@./fixtures/flags.txt"},
+			expected:    []string{"@foo", "--", "@./fixtures/flags.txt"},
+		},
+		{
+			description: "cycle",
+			args:        []string{"@./fixtures/flags_cycle.txt"},
+			err:         errors.New("response file fixtures/flags_cycle.txt includes itself"),
+		},
+		{
+			description: "missing",
+			args:        []string{"@./fixtures/missing.txt"},
+			err:         errors.New("read response file: open fixtures/missing.txt: no such file or directory"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			result, err := ExpandResponseFiles(testcase.args)
+
+			if testcase.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, testcase.expected, result)
+			} else {
+				require.NotNil(t, err)
+				require.EqualError(t, testcase.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadWithResponseFiles(t *testing.T) {
+	testcases := []struct {
+		description string
+		args        []string
+		expected    *sourcedConfig
+	}{
+		{
+			description: "value from file",
+			args:        []string{"--cert", "@./fixtures/cert.pem", "@./fixtures/flags_sourced.txt"},
+			expected: &sourcedConfig{
+				Cert:    "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+				Payload: "@raw",
+				Name:    "job",
+			},
+		},
+		{
+			description: "value from file with equal sign",
+			args:        []string{"--cert=@./fixtures/cert.pem", "--name", "@@job"},
+			expected: &sourcedConfig{
+				Cert: "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+				Name: "@@job",
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			element := &sourcedConfig{}
+			configo := &Configo{Loader: &FlagLoader{DisallowUnused: true}, ResponseFiles: true}
+
+			require.NoError(t, configo.Load(element, testcase.args))
+			assert.Equal(t, testcase.expected, element)
+		})
+	}
+}
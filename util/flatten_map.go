@@ -4,12 +4,22 @@ import (
 	"strings"
 )
 
-// FlattenMap implements node.FlattenMap with key's usage record
+// segmentsSeparator separates key and its segments in id of value set with
+// segments, which never appears in dot-case key.
+const segmentsSeparator = "\x00"
+
+// FlattenMap implements node.FlattenMap with key's usage and source record,
+// where values are recorded by id, which is key itself, or key followed by
+// segments when value is set with segments
 type FlattenMap struct {
 	keys        []string
 	data        map[string]string
 	used        map[string]bool
 	originalKey map[string]string
+	source      map[string]string
+	variants    map[string][]string
+	nulls       map[string]bool
+	empties     map[string]bool
 }
 
 // NewFlattenMap initialize a flatten map
@@ -18,6 +28,10 @@ func NewFlattenMap() *FlattenMap {
 		data:        make(map[string]string),
 		used:        make(map[string]bool),
 		originalKey: make(map[string]string),
+		source:      make(map[string]string),
+		variants:    make(map[string][]string),
+		nulls:       make(map[string]bool),
+		empties:     make(map[string]bool),
 	}
 }
 
@@ -28,10 +42,76 @@ func (m *FlattenMap) Value(key string) (value string, ok bool) {
 	return value, ok
 }
 
+// Lookup returns value of key without recording key's usage
+func (m *FlattenMap) Lookup(key string) (value string, ok bool) {
+	value, ok = m.data[key]
+	return value, ok
+}
+
+// IsNull implements node.NullableFlattenMap with recording key's usage
+func (m *FlattenMap) IsNull(key string) bool {
+	if !m.nulls[key] || m.empties[key] {
+		return false
+	}
+	m.used[key] = true
+	return true
+}
+
+// IsEmpty implements node.NullableFlattenMap with recording key's usage
+func (m *FlattenMap) IsEmpty(key string) bool {
+	if !m.empties[key] {
+		return false
+	}
+	m.used[key] = true
+	return true
+}
+
+// MapValues replaces each non-null value by mapper in order by when they were
+// set, where values are replaced after all are mapped, so mapper always sees
+// original values
+func (m *FlattenMap) MapValues(mapper func(key, value string) (string, error)) error {
+	values := make(map[string]string, len(m.keys))
+	for _, id := range m.keys {
+		if m.nulls[id] {
+			continue
+		}
+		value, err := mapper(keyOf(id), m.data[id])
+		if err != nil {
+			return err
+		}
+		values[id] = value
+	}
+	for id, value := range values {
+		m.data[id] = value
+	}
+	return nil
+}
+
+// SegmentedValue implements node.SegmentedFlattenMap, which prefers value set
+// with the same segments to value set without segments, and records usage of
+// both since the latter one is overridden
+func (m *FlattenMap) SegmentedValue(key string, segments []string) (value string, ok bool) {
+	if id := segmentsID(key, segments); m.hasID(id) {
+		m.used[id] = true
+		m.used[key] = true
+		return m.data[id], true
+	}
+	return m.Value(key)
+}
+
+// SegmentedLookup returns value of key like SegmentedValue without recording
+// key's usage
+func (m *FlattenMap) SegmentedLookup(key string, segments []string) (value string, ok bool) {
+	if id := segmentsID(key, segments); m.hasID(id) {
+		return m.data[id], true
+	}
+	return m.Lookup(key)
+}
+
 // ChildrenByPrefix implements node.FlattenMap
 func (m *FlattenMap) ChildrenByPrefix(prefix string) (keys []string) {
-	for _, key := range m.keys {
-		if strings.HasPrefix(key, prefix) {
+	for _, id := range m.keys {
+		if key := keyOf(id); strings.HasPrefix(key, prefix) {
 			if key = strings.SplitN(key[len(prefix):], CharDot, 2)[0]; key != "" {
 				keys = append(keys, key)
 			}
@@ -42,24 +122,152 @@ func (m *FlattenMap) ChildrenByPrefix(prefix string) (keys []string) {
 
 // Set format key to dot-case and set key to value, which also clear key's usage
 func (m *FlattenMap) Set(originalKey, value string) {
+	m.SetWithSource(originalKey, value, "")
+}
+
+// SetWithSource sets key to value like Set, and records where value comes from
+func (m *FlattenMap) SetWithSource(originalKey, value, source string) {
+	m.set(ToDotCase(originalKey), originalKey, value, source, false)
+}
+
+// SetNull sets key to explicit null like Set, which also drops keys under it,
+// to reset pointer to nil and clear slice or map
+func (m *FlattenMap) SetNull(originalKey string) {
+	m.set(ToDotCase(originalKey), originalKey, "", "", true)
+}
+
+// SetEmpty sets key to explicit empty list or mapping like SetNull, which
+// clears slice or map but keeps struct
+func (m *FlattenMap) SetEmpty(originalKey string) {
 	key := ToDotCase(originalKey)
-	if _, set := m.data[key]; set {
-		for i := range m.keys {
-			if m.keys[i] == key {
-				m.keys = append(m.keys[:i], m.keys[i+1:]...)
-				break
+	m.set(key, originalKey, "", "", true)
+	m.empties[key] = true
+}
+
+// SetWithSegments sets key joined by segments to value like Set, but only for
+// nodes with the same segments, to tell apart nodes sharing the same key
+func (m *FlattenMap) SetWithSegments(originalKey, value string, segments []string) {
+	dotSegments := make([]string, len(segments))
+	for i := range segments {
+		dotSegments[i] = ToDotCase(segments[i])
+	}
+	m.set(segmentsID(strings.Join(dotSegments, CharDot), dotSegments), originalKey, value, "", false)
+}
+
+// set sets value by id, where value set without segments also overrides
+// values set with segments of the same key, and null also overrides values
+// under the key
+func (m *FlattenMap) set(id, originalKey, value, source string, null bool) {
+	existed := m.hasID(id)
+	if existed {
+		m.delete(id)
+	}
+	if key := keyOf(id); key == id {
+		for _, variant := range m.variants[key] {
+			m.delete(variant)
+		}
+		delete(m.variants, key)
+	} else if !existed {
+		m.variants[key] = append(m.variants[key], id)
+	}
+	if null {
+		prefix := keyOf(id) + CharDot
+		for _, other := range append([]string{}, m.keys...) {
+			if strings.HasPrefix(keyOf(other), prefix) {
+				m.delete(other)
 			}
 		}
+		m.nulls[id] = true
+	}
+	m.keys = append(m.keys, id)
+	m.originalKey[id] = originalKey
+	m.data[id] = value
+	m.source[id] = source
+}
+
+// Delete deletes key and its values set with segments
+func (m *FlattenMap) Delete(key string) {
+	for _, variant := range m.variants[key] {
+		m.delete(variant)
+	}
+	delete(m.variants, key)
+	m.delete(key)
+}
+
+func (m *FlattenMap) delete(id string) {
+	for i := range m.keys {
+		if m.keys[i] == id {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	delete(m.originalKey, id)
+	delete(m.data, id)
+	delete(m.source, id)
+	delete(m.used, id)
+	delete(m.nulls, id)
+	delete(m.empties, id)
+}
+
+func (m *FlattenMap) hasID(id string) bool {
+	_, ok := m.data[id]
+	return ok
+}
+
+// Source returns where value of key, or the first key under it, comes from
+func (m *FlattenMap) Source(key string) string {
+	if source, ok := m.source[key]; ok {
+		return source
+	}
+	for _, id := range m.keys {
+		if k := keyOf(id); k == key || strings.HasPrefix(k, key+CharDot) {
+			return m.source[id]
+		}
+	}
+	return ""
+}
+
+// Merge sets all keys of other in order by when they were set, and records
+// source of values without source as defaultSource
+func (m *FlattenMap) Merge(other *FlattenMap, defaultSource string) {
+	for _, id := range other.keys {
+		source := other.source[id]
+		if source == "" {
+			source = defaultSource
+		}
+		m.set(id, other.originalKey[id], other.data[id], source, other.nulls[id])
+		if other.empties[id] {
+			m.empties[id] = true
+		}
+	}
+}
+
+// MarkUsed records keys which were used in other as used, including keys
+// cleared by used null or empty in other
+func (m *FlattenMap) MarkUsed(other *FlattenMap) {
+	for _, id := range m.keys {
+		if other.used[id] || other.isCleared(keyOf(id)) {
+			m.used[id] = true
+		}
+	}
+}
+
+// isCleared checks whether any parent of key is used null or empty
+func (m *FlattenMap) isCleared(key string) bool {
+	for i := range key {
+		if parent := key[:i]; key[i] == CharDot[0] && m.nulls[parent] && m.used[parent] {
+			return true
+		}
 	}
-	m.keys = append(m.keys, key)
-	m.originalKey[key] = originalKey
-	m.data[key] = value
-	delete(m.used, key)
+	return false
 }
 
 // Keys returns keys in order by when they were set
-func (m *FlattenMap) Keys() []string {
-	return m.keys
+func (m *FlattenMap) Keys() (keys []string) {
+	for _, id := range m.keys {
+		keys = append(keys, keyOf(id))
+	}
+	return keys
 }
 
 // Values returns values in order by when they were set
@@ -74,10 +282,18 @@ func (m *FlattenMap) Values() []string {
 // UnusedKeys returns unused keys after they were set
 func (m *FlattenMap) UnusedKeys(escapedKeys []string) (keys []string) {
 	escaped := StringsToSet(escapedKeys)
-	for _, key := range m.keys {
-		if !(m.used[key] || escaped[key]) {
-			keys = append(keys, m.originalKey[key])
+	for _, id := range m.keys {
+		if !(m.used[id] || escaped[keyOf(id)]) {
+			keys = append(keys, m.originalKey[id])
 		}
 	}
 	return keys
 }
+
+func segmentsID(key string, segments []string) string {
+	return key + segmentsSeparator + strings.Join(segments, segmentsSeparator)
+}
+
+func keyOf(id string) string {
+	return strings.SplitN(id, segmentsSeparator, 2)[0]
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// globStar matches zero or more directories in pattern of Glob.
+const globStar = "**"
+
+// HasMeta checks whether path contains any pattern meta character of Glob.
+func HasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
+
+// Glob returns names of files matching pattern sorted lexically like
+// filepath.Glob, and additionally supports ** to match zero or more
+// directories like /etc/app/**/*.yaml.
+func Glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, globStar) {
+		return filepath.Glob(pattern)
+	}
+
+	// Walk from the longest directory without meta character
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	rootSegments := 0
+	for rootSegments < len(segments)-1 && !HasMeta(segments[rootSegments]) {
+		rootSegments++
+	}
+	root := filepath.FromSlash(strings.Join(segments[:rootSegments], "/"))
+	if root == "" {
+		root = "."
+		if strings.HasPrefix(pattern, "/") {
+			root = "/"
+		}
+	}
+
+	var matches []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) || os.IsPermission(err) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		matched, err := matchGlobSegments(segments[rootSegments:], strings.Split(filepath.ToSlash(rel), "/"))
+		if err != nil {
+			return err
+		}
+		if matched {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchGlobSegments matches path segments by pattern segments, where ** matches
+// zero or more segments.
+func matchGlobSegments(patterns, segments []string) (bool, error) {
+	for len(patterns) > 0 {
+		if patterns[0] == globStar {
+			for i := 0; i <= len(segments); i++ {
+				if matched, err := matchGlobSegments(patterns[1:], segments[i:]); err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+		if len(segments) == 0 {
+			return false, nil
+		}
+		if matched, err := filepath.Match(patterns[0], segments[0]); err != nil || !matched {
+			return false, err
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(segments) == 0, nil
+}